@@ -2,6 +2,8 @@ package cvblog
 
 import (
 	"bytes"
+	"fmt"
+	"html"
 	"html/template"
 	"regexp"
 	"strings"
@@ -12,6 +14,9 @@ import (
 
 const (
 	defaultCategory = "心得体会"
+	// summaryLength is the number of runes of an excerpt without a
+	// `<!--more-->` marker.
+	summaryLength = 200
 )
 
 var (
@@ -21,8 +26,20 @@ var (
 	reTag      *regexp.Regexp
 	reStatus   *regexp.Regexp
 	reURL      *regexp.Regexp
+	reUpdated  *regexp.Regexp
+	// reHeaderLine is any `Key: value` line of the header
+	reHeaderLine *regexp.Regexp
 )
 
+// legacyKeys are the keys of the metadata lines before the first blank line.
+var legacyKeys = map[string]bool{"Date": true, "Title": true, "Category": true, "Tags": true, "Status": true, "URL": true, "Updated": true}
+
+var markdownOptions = markdown.Options{
+	Highlight:      &markdown.HighlightOptions{},
+	HeadingAnchors: true,
+	Typography:     &markdown.TypographyOptions{},
+}
+
 type Article struct {
 	Time     time.Time
 	Date     string
@@ -31,7 +48,30 @@ type Article struct {
 	Tags     []string
 	Status   string
 	URL      string
+	// Slug is the `URL:` or `slug:` of the article, or else it is made
+	// from the title, URL is made of it by Permalink.
+	Slug string
+	// Updated is the time of the last change of the article, or zero.
+	Updated     time.Time
+	Draft       bool
+	Description string
+	Author      string
+	Cover       string
+	// Params holds the keys of the front matter which are not fields of
+	// the article, such as `{{.Params.series}}`.
+	Params   map[string]interface{}
 	Body     template.HTML
+	TOC      []*markdown.TOCEntry
+	Warnings []markdown.Warning
+	// Math reports whether the post needs KaTeX to typeset its formulas.
+	Math bool
+	// Mermaid reports whether the post has diagrams which are drawn by
+	// mermaid.js, the others are rendered to SVG.
+	Mermaid bool
+	// Excerpt is the beginning of the body up to `<!--more-->`, or cut to
+	// summaryLength runes, ExcerptText is the same as plain text.
+	Excerpt     template.HTML
+	ExcerptText string
 }
 
 type ArticleSortByTime []*Article
@@ -39,70 +79,280 @@ type ArticleSortByTime []*Article
 func init() {
 	reDate = regexp.MustCompile(`^Date: (.+)$`)
 	reTitle = regexp.MustCompile(`^Title: (.+)$`)
+	reCategory = regexp.MustCompile(`^Category: (.+)$`)
 	reTag = regexp.MustCompile(`^Tags: (.+)$`)
 	reStatus = regexp.MustCompile(`^Status: (.+)$`)
 	reURL = regexp.MustCompile(`^URL: (.+)$`)
+	reUpdated = regexp.MustCompile(`^Updated: (.+)$`)
+	reHeaderLine = regexp.MustCompile(`^([A-Za-z][\w-]*):[ \t]*(.*)$`)
 }
 
+// NewArticle parses input with DefaultShortcodes, shortcodes which fail are
+// kept as text and reported in Warnings, as are the problems of the
+// metadata. The `post` shortcode only works in BuildArticles, which knows
+// the other articles.
 func NewArticle(input []byte) *Article {
-	content := bytes.SplitN(input, []byte("\n\n"), 2)
+	result, h := parseHeader(input)
+	errs := result.renderBody(h.body, DefaultShortcodes, nil, h.offset)
+
+	warnings := make([]markdown.Warning, 0, len(h.diags)+len(errs)+len(result.Warnings))
+	for _, d := range h.diags {
+		warnings = append(warnings, d.warning())
+	}
+	result.Warnings = append(append(warnings, errs...), result.Warnings...)
+
+	return result
+}
+
+// ParseArticle parses the article of the file name, the returned error is
+// the Diagnostics of every problem of its metadata and shortcodes. The
+// article is returned even if it has problems.
+func ParseArticle(name string, input []byte) (*Article, error) {
+	articles, err := BuildArticles([]string{name}, [][]byte{input}, DefaultShortcodes)
+	return articles[0], err
+}
+
+// BuildArticles parses every input, read from the file of the same index
+// of names, with the shortcodes, `post` resolves to any of them. The
+// problems of all the articles, such as missing metadata, duplicate URLs
+// and shortcodes which fail, are returned at once as Diagnostics.
+func BuildArticles(names []string, inputs [][]byte, shortcodes *Shortcodes) ([]*Article, error) {
+	articles := make([]*Article, len(inputs))
+	headers := make([]*header, len(inputs))
+	posts := make(map[string]*Article)
+	urls := make(map[string]string)
+
+	var diags Diagnostics
+	var generated []int
+	for i, input := range inputs {
+		articles[i], headers[i] = parseHeader(input)
+		for _, d := range headers[i].diags {
+			d.File = names[i]
+			diags = append(diags, d)
+		}
+
+		if headers[i].generated {
+			generated = append(generated, i)
+			continue
+		}
+		if first, exist := urls[articles[i].URL]; exist {
+			diags = append(diags, Diagnostic{
+				File:    names[i],
+				Line:    headers[i].lines["url"],
+				Field:   "url",
+				Message: fmt.Sprintf("duplicate url %s, which is used by %s", articles[i].URL, first),
+			})
+			continue
+		}
+		urls[articles[i].URL] = names[i]
+	}
+	resolveSlugs(articles, names, generated, urls)
 
+	for _, a := range articles {
+		if _, exist := posts[a.Slug]; !exist {
+			posts[a.Slug] = a
+		}
+	}
+
+	for i, a := range articles {
+		for _, w := range a.renderBody(headers[i].body, shortcodes, posts, headers[i].offset) {
+			diags = append(diags, Diagnostic{File: names[i], Line: w.Line, Column: w.Column, Message: w.Message})
+		}
+	}
+	if len(diags) > 0 {
+		diags.sort()
+		return articles, diags
+	}
+
+	return articles, nil
+}
+
+// header is the metadata of an article, which is parsed before its body.
+type header struct {
+	body []byte
+	// offset is the number of lines before the body
+	offset int
+	// lines is the line of every field which is given
+	lines map[string]int
+	diags Diagnostics
+	// generated reports whether the slug is made from the title
+	generated bool
+}
+
+func (h *header) report(line int, field, msg string) {
+	h.diags = append(h.diags, Diagnostic{Line: line, Field: field, Message: msg})
+}
+
+// require reports the field as missing if it is empty and no problem of
+// it is reported yet.
+func (h *header) require(field string, empty bool) {
+	if !empty {
+		return
+	}
+	for _, d := range h.diags {
+		if d.Field == field {
+			return
+		}
+	}
+	line := h.lines[field]
+	if line == 0 {
+		line = 1
+	}
+	h.report(line, field, "missing "+field)
+}
+
+// parseHeader reads the YAML or TOML front matter of input, or else the
+// metadata lines before the first blank line, the rest is the body. A
+// missing title, date or URL, an invalid value or an unknown key is
+// reported in the diagnostics of the header.
+func parseHeader(input []byte) (*Article, *header) {
+	input = bytes.Replace(input, []byte("\r\n"), []byte("\n"), -1)
 	result := &Article{
-		Body:     template.HTML(markdown.Render(content[1])),
 		Category: defaultCategory,
+		Params:   make(map[string]interface{}),
+	}
+	h := &header{body: input, lines: make(map[string]int)}
+
+	if format, lines, body := splitFrontMatter(input); format != 0 {
+		h.body, h.offset = body, len(lines)+2
+		result.parseFrontMatter(format, lines, h)
+	} else if first := bytes.SplitN(input, []byte("\n"), 2)[0]; reHeaderLine.Match(first) {
+		result.parseLegacyHeader(input, h)
 	}
+
+	h.require("title", result.Title == "")
+	h.require("date", result.Time.IsZero())
+	if !states[result.status()] {
+		h.report(h.lines["status"], "status", fmt.Sprintf("unknown status %s", result.Status))
+	}
+	h.diags.sort()
+
+	if result.Slug == "" {
+		result.Slug = Slugify(html.UnescapeString(string(result.Title)))
+		if result.Slug == "" {
+			result.Slug = "post"
+		}
+		h.generated = true
+	}
+	result.URL = result.permalink(Permalink)
+
+	return result, h
+}
+
+// parseLegacyHeader reads the `Key: value` lines before the first blank
+// line of input.
+func (a *Article) parseLegacyHeader(input []byte, h *header) {
+	content := bytes.SplitN(input, []byte("\n\n"), 2)
 	prefixs := bytes.Split(content[0], []byte("\n"))
-	for _, prefix := range prefixs {
-		if reDate.Match(prefix) {
+	h.body, h.offset = nil, len(prefixs)+1
+	if len(content) > 1 {
+		h.body = content[1]
+	}
+
+	for i, prefix := range prefixs {
+		line := i + 1
+		ret := reHeaderLine.FindSubmatch(prefix)
+		if ret == nil {
+			h.report(line, "", fmt.Sprintf("%q is not a Key: value line", prefix))
+			continue
+		}
+		field := strings.ToLower(string(ret[1]))
+		h.lines[field] = line
+
+		switch {
+		case reDate.Match(prefix):
 			date := reDate.FindSubmatch(prefix)
-			t, err := time.Parse("2006-01-02 15:04", string(date[1]))
+			t, err := parseDate(string(date[1]))
 			if err != nil {
-				t = time.Now()
+				h.report(line, "date", err.Error())
+				continue
 			}
-			result.Time = t
-			result.Date = t.Format("2006-01-02 15:04")
-		}
-		if reTitle.Match(prefix) {
+			a.setDate(t)
+
+		case reUpdated.Match(prefix):
+			updated := reUpdated.FindSubmatch(prefix)
+			t, err := parseDate(string(updated[1]))
+			if err != nil {
+				h.report(line, "updated", err.Error())
+				continue
+			}
+			a.Updated = t.In(Location)
+
+		case reTitle.Match(prefix):
 			title := reTitle.FindSubmatch(prefix)
-			result.Title = template.HTML(title[1])
-		}
-		if reTag.Match(prefix) {
+			a.Title = template.HTML(template.HTMLEscapeString(string(title[1])))
+
+		case reCategory.Match(prefix):
+			category := reCategory.FindSubmatch(prefix)
+			a.Category = strings.TrimSpace(string(category[1]))
+
+		case reTag.Match(prefix):
 			tags := reTag.FindSubmatch(prefix)
 			ts := strings.Split(string(tags[1]), ",")
-			result.Tags = []string{}
+			a.Tags = []string{}
 			for _, v := range ts {
 				trim := strings.Trim(v, " ")
-				result.Tags = append(result.Tags, trim)
+				a.Tags = append(a.Tags, trim)
 			}
-		}
-		if reStatus.Match(prefix) {
+
+		case reStatus.Match(prefix):
 			status := reStatus.FindSubmatch(prefix)
-			result.Status = string(status[1])
-		}
-		if reURL.Match(prefix) {
+			a.Status = string(status[1])
+
+		case reURL.Match(prefix):
 			urls := reURL.FindSubmatch(prefix)
-			if bytes.HasSuffix(urls[1], []byte(".html")) {
-				result.URL = string(urls[1])
-			} else {
-				result.URL = string(urls[1]) + ".html"
-			}
+			a.setSlug(string(urls[1]))
+
+		case legacyKeys[string(ret[1])]:
+			// an empty value leaves the field unset
+
+		default:
+			h.report(line, field, "unknown key")
 		}
 	}
+}
 
-	return result
+// renderBody expands the shortcodes of body and renders it to Body, the
+// shortcodes which fail are returned. The lines of the warnings are moved
+// down by offset, the lines of the header.
+func (a *Article) renderBody(body []byte, shortcodes *Shortcodes, posts map[string]*Article, offset int) []markdown.Warning {
+	expander := &shortcodeExpander{shortcodes: shortcodes, posts: posts}
+	parser := markdown.NewParser()
+	parser.Enable(markdown.DefinitionLists | markdown.Abbreviations | markdown.HeadingIDs | markdown.Diagrams)
+	parser.AddBlock(shortcodeBlocks{expander})
+	parser.AddInline(shortcodeInlines{expander})
+	doc := parser.Parse(body)
+
+	renderer := markdown.NewRenderer(markdownOptions)
+	renderer.Register(&shortcodeNode{}, renderShortcode)
+
+	a.Body = template.HTML(renderer.Render(doc))
+	excerpt, _ := markdown.Excerpt(doc, summaryLength)
+	a.Excerpt = template.HTML(renderer.Render(excerpt))
+	a.ExcerptText = string(markdown.RenderText(excerpt))
+	a.TOC = markdown.TableOfContents(doc)
+	a.Warnings = append(a.Warnings, markdown.Check(doc)...)
+	a.Math = markdown.HasMath(doc)
+	a.Mermaid = markdown.HasMermaid(doc)
+
+	for i := range a.Warnings {
+		a.Warnings[i].Line += offset
+	}
+	for i := range expander.errs {
+		expander.errs[i].Line += offset
+	}
+
+	return expander.errs
 }
 
 func (a *Article) SetCategory(c string) {
 	a.Category = c
 }
 
-func (a *Article) Summary() string {
-	length := len(a.Body)
-	if length > 500 {
-		length = 500
-	}
-
-	return string(a.Body)[:length]
+// Summary is the excerpt of the article, it is well-formed HTML which is
+// safe to show on the index page.
+func (a *Article) Summary() template.HTML {
+	return a.Excerpt
 }
 
 func (byTime ArticleSortByTime) Len() int {
@@ -2,6 +2,7 @@ package cvblog
 
 import (
 	"sort"
+	"strings"
 	"testing"
 )
 
@@ -32,3 +33,61 @@ func TestSortByDate(t *testing.T) {
 	t.Log(articles[0].Date)
 	t.Log(articles[0].Title)
 }
+
+func TestArticleTOC(t *testing.T) {
+	input := "Date: 2012-10-25 12:22\nTitle: 目录\nURL: toc\n\n## 第一节\n\n### 小节\n\n## 第二节"
+	paper := NewArticle([]byte(input))
+
+	if len(paper.TOC) != 2 || paper.TOC[0].ID != "第一节" || len(paper.TOC[0].Children) != 1 {
+		t.Fatalf("article toc fail, %+v", paper.TOC)
+	}
+}
+
+func TestArticleMath(t *testing.T) {
+	input := "Date: 2012-10-25 12:22\nTitle: 公式\nURL: math\n\n能量 $E = mc^2$"
+	paper := NewArticle([]byte(input))
+
+	if !paper.Math || !strings.Contains(string(paper.Body), `<span class="math inline">`) {
+		t.Fatalf("article math fail, %s", paper.Body)
+	}
+}
+
+func TestArticleDiagram(t *testing.T) {
+	input := []string{
+		"Date: 2012-10-25 12:22\nTitle: 图\nURL: diagram\n\n```mermaid\ngraph LR\n  A --> B\n```",
+		"Date: 2012-10-25 12:22\nTitle: 图\nURL: diagram\n\n```mermaid\ngantt\n  title x\n```",
+	}
+
+	output := []string{"<div class=\"diagram mermaid\">\n<svg", "<div class=\"mermaid\">\ngantt"}
+	mermaid := []bool{false, true}
+
+	for i, v := range input {
+		paper := NewArticle([]byte(v))
+		if paper.Mermaid != mermaid[i] || !strings.Contains(string(paper.Body), output[i]) {
+			t.Fatalf("article diagram fail, %v %s", paper.Mermaid, paper.Body)
+		}
+	}
+}
+
+func TestArticleSummary(t *testing.T) {
+	input := []string{
+		"Date: 2012-10-25 12:22\nTitle: 摘要\nURL: summary\n\n第一段*强调*。\n\n<!--more-->\n\n第二段。",
+		"Date: 2012-10-25 12:22\nTitle: 摘要\nURL: summary\n\n" + strings.Repeat("这是一个很长的句子，", 30),
+	}
+
+	output := []string{
+		"\n<p>第一段<em>强调</em>。</p>\n",
+		"\n<p>" + strings.Repeat("这是一个很长的句子，", 19) + "这是一个很长的句子…</p>\n",
+	}
+	text := []string{
+		"第一段强调。",
+		strings.Repeat("这是一个很长的句子，", 19) + "这是一个很长的句子…",
+	}
+
+	for i, v := range input {
+		paper := NewArticle([]byte(v))
+		if string(paper.Summary()) != output[i] || paper.ExcerptText != text[i] {
+			t.Fatalf("article summary fail, [%s] [%s] vs [%s] [%s]", paper.Summary(), paper.ExcerptText, output[i], text[i])
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package cvblog
+
+import (
+	"fmt"
+	"time"
+)
+
+// Location is the time zone of the site, dates without an offset are in
+// it and all dates are shown in it. It must be set before the articles are
+// parsed.
+var Location = time.UTC
+
+// dateLayouts are the date formats of the metadata, tried in order; the
+// ones with an offset are ISO 8601 dates.
+var dateLayouts = []string{
+	"2006-01-02 15:04",
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04Z07:00",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02 15:04 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseDate parses value in any of dateLayouts, in Location unless it has
+// an offset.
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, Location); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// setDate sets the date of the article to t in Location.
+func (a *Article) setDate(t time.Time) {
+	a.Time = t.In(Location)
+	a.Date = a.Time.Format("2006-01-02 15:04")
+}
+
+// SetModified sets Updated to t, the time the file of the article was
+// last modified, which is the mtime of the file with `cvblog -mtime`,
+// unless the metadata gives it or t is before the date of the article.
+func (a *Article) SetModified(t time.Time) {
+	if a.Updated.IsZero() && t.After(a.Time) {
+		a.Updated = t.In(Location)
+	}
+}
+
+// Modified is the time of the last change of the article, which is its
+// date if it has not been updated.
+func (a *Article) Modified() time.Time {
+	if a.Updated.IsZero() {
+		return a.Time
+	}
+
+	return a.Updated
+}
+
+// DateRFC3339 is the date of the article in RFC 3339, for `datetime`
+// attributes and feeds.
+func (a *Article) DateRFC3339() string {
+	return a.Time.Format(time.RFC3339)
+}
+
+// UpdatedRFC3339 is Modified in RFC 3339.
+func (a *Article) UpdatedRFC3339() string {
+	return a.Modified().Format(time.RFC3339)
+}
@@ -0,0 +1,79 @@
+package cvblog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArticleTimeZone(t *testing.T) {
+	defer func(location *time.Location) { Location = location }(Location)
+	Location = time.FixedZone("CST", 8*3600)
+
+	input := []string{
+		"Date: 2012-10-25 12:22\nTitle: a\nURL: a\n\nbody",
+		"Date: 2012-10-25T12:22:00Z\nTitle: a\nURL: a\n\nbody",
+		"Date: 2012-10-25 12:22+02:00\nTitle: a\nURL: a\n\nbody",
+		"Date: 2012-10-25T12:22:30.5-0700\nTitle: a\nURL: a\n\nbody",
+		"---\ntitle: a\ndate: 2012-10-25\nslug: a\n---\nbody",
+		"+++\ntitle = \"a\"\ndate = 2012-10-25T12:22:00+08:00\nurl = \"a\"\n+++\nbody",
+	}
+
+	output := [][]string{
+		{"2012-10-25 12:22", "2012-10-25T12:22:00+08:00"},
+		{"2012-10-25 20:22", "2012-10-25T20:22:00+08:00"},
+		{"2012-10-25 18:22", "2012-10-25T18:22:00+08:00"},
+		{"2012-10-26 03:22", "2012-10-26T03:22:30+08:00"},
+		{"2012-10-25 00:00", "2012-10-25T00:00:00+08:00"},
+		{"2012-10-25 12:22", "2012-10-25T12:22:00+08:00"},
+	}
+
+	for i, v := range input {
+		paper, err := ParseArticle("a.md", []byte(v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if paper.Date != output[i][0] || paper.DateRFC3339() != output[i][1] {
+			t.Fatalf("article time zone fail, [%s %s] vs [%s %s]", paper.Date, paper.DateRFC3339(), output[i][0], output[i][1])
+		}
+	}
+}
+
+func TestArticleUpdated(t *testing.T) {
+	input := []string{
+		"Date: 2012-10-25 12:22\nTitle: a\nURL: a\n\nbody",
+		"Date: 2012-10-25 12:22\nUpdated: 2013-01-02 08:00\nTitle: a\nURL: a\n\nbody",
+		"---\ntitle: a\ndate: 2012-10-25 12:22\nlastmod: 2013-01-02T08:00:00Z\nslug: a\n---\nbody",
+	}
+
+	output := []string{
+		"2012-10-25T12:22:00Z",
+		"2013-01-02T08:00:00Z",
+		"2013-01-02T08:00:00Z",
+	}
+
+	for i, v := range input {
+		paper, err := ParseArticle("a.md", []byte(v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if paper.UpdatedRFC3339() != output[i] {
+			t.Fatalf("article updated fail, [%s] vs [%s]", paper.UpdatedRFC3339(), output[i])
+		}
+
+		paper.SetModified(time.Date(2014, 5, 6, 7, 8, 9, 0, time.UTC))
+		if i > 0 && paper.UpdatedRFC3339() != output[i] || i == 0 && paper.UpdatedRFC3339() != "2014-05-06T07:08:09Z" {
+			t.Fatalf("article modified fail, [%s]", paper.UpdatedRFC3339())
+		}
+	}
+
+	paper := NewArticle([]byte(input[0]))
+	paper.SetModified(time.Date(2011, 5, 6, 7, 8, 9, 0, time.UTC))
+	if !paper.Updated.IsZero() {
+		t.Fatalf("article modified before date fail, %s", paper.Updated)
+	}
+
+	_, err := ParseArticle("a.md", []byte("Date: 2012-10-25 12:22\nUpdated: later\nTitle: a\nURL: a\n\nbody"))
+	if err == nil || err.Error() != `a.md:2: updated: invalid date "later"` {
+		t.Fatalf("article updated error fail, %v", err)
+	}
+}
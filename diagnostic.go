@@ -0,0 +1,77 @@
+package cvblog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cvley/cvblog/markdown"
+)
+
+// Diagnostic is a problem of an article. Line is 0 if the problem is not
+// at a line, and Field is the metadata key it is about, if any.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Field   string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.File != "" {
+		b.WriteString(d.File + ":")
+	}
+	if d.Line > 0 {
+		b.WriteString(fmt.Sprintf("%d:", d.Line))
+		if d.Column > 0 {
+			b.WriteString(fmt.Sprintf("%d:", d.Column))
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	if d.Field != "" {
+		b.WriteString(d.Field + ": ")
+	}
+	b.WriteString(d.Message)
+
+	return b.String()
+}
+
+// warning is d as a warning of the article.
+func (d Diagnostic) warning() markdown.Warning {
+	msg := d.Message
+	if d.Field != "" {
+		msg = d.Field + ": " + msg
+	}
+
+	return markdown.Warning{Position: markdown.Position{Line: d.Line, Column: d.Column}, Message: msg}
+}
+
+// Diagnostics is every problem found in a build, it is returned as a
+// single error.
+type Diagnostics []Diagnostic
+
+func (ds Diagnostics) Error() string {
+	lines := make([]string, len(ds))
+	for i, d := range ds {
+		lines[i] = d.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// sort orders the diagnostics by file and line.
+func (ds Diagnostics) sort() {
+	sort.SliceStable(ds, func(i, j int) bool {
+		if ds[i].File != ds[j].File {
+			return ds[i].File < ds[j].File
+		}
+		if ds[i].Line != ds[j].Line {
+			return ds[i].Line < ds[j].Line
+		}
+		return ds[i].Column < ds[j].Column
+	})
+}
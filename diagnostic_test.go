@@ -0,0 +1,69 @@
+package cvblog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseArticle(t *testing.T) {
+	input := []string{
+		"Date: 2012-10-25 12:22\nTitle: 无正文\nURL: a",
+		"Date: yesterday\nTitle: x\nURL: a\n\nbody",
+		"Date: 2012-10-25 12:22\nTitle: \nURL: a\n\nbody",
+		"Date: 2012-10-25 12:22\nTitle: x\nURL: a\nAuthor: me\n\nbody",
+		"Date: 2012-10-25 12:22\nTitle: x\nURL: a\nnot a header\n\nbody",
+		"just markdown",
+		"---\ntitle: x\nslug: a\ndate: 2024-03-01\nkey: [1\n---\nbody",
+	}
+
+	output := []string{
+		"",
+		`a.md:1: date: invalid date "yesterday"`,
+		"a.md:2: title: missing title",
+		"a.md:4: author: unknown key",
+		`a.md:4: "not a header" is not a Key: value line`,
+		"a.md:1: title: missing title\na.md:1: date: missing date",
+		"a.md:1: title: missing title\na.md:1: date: missing date\na.md:5: key: unterminated list [1",
+	}
+
+	for i, v := range input {
+		paper, err := ParseArticle("a.md", []byte(v))
+		if paper == nil {
+			t.Fatalf("parse article %d fail, no article", i)
+		}
+		msg := ""
+		if err != nil {
+			msg = err.Error()
+		}
+		if msg != output[i] {
+			t.Fatalf("parse article fail, [%s] vs [%s]", msg, output[i])
+		}
+	}
+}
+
+func TestBuildArticlesDiagnostics(t *testing.T) {
+	names := []string{"a.md", "b.md", "c.md"}
+	input := [][]byte{
+		[]byte("Date: 2012-10-25 12:22\nTitle: a\nURL: same\n\nbody"),
+		[]byte("Date: 2012-10-26 12:22\nTitle: b\nURL: same.html\n\n{{< tweet 1 >}}"),
+		[]byte("Title: c\nURL: c\n\nbody"),
+	}
+
+	articles, err := BuildArticles(names, input, NewShortcodes())
+	if len(articles) != 3 {
+		t.Fatalf("build articles fail, %d articles", len(articles))
+	}
+
+	output := "b.md:3: url: duplicate url same.html, which is used by a.md\nb.md:5:1: unknown shortcode \"tweet\"\nc.md:1: date: missing date"
+	if err == nil || err.Error() != output {
+		t.Fatalf("build articles diagnostics fail, [%v] vs [%s]", err, output)
+	}
+
+	diags := err.(Diagnostics)
+	if diags[0].File != "b.md" || diags[0].Field != "url" || diags[0].Line != 3 {
+		t.Fatalf("build articles diagnostic fail, %+v", diags[0])
+	}
+	if !strings.HasPrefix(articles[1].URL, "same") {
+		t.Fatalf("build articles url fail, %s", articles[1].URL)
+	}
+}
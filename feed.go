@@ -0,0 +1,140 @@
+package cvblog
+
+import (
+	"encoding/xml"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// atomFeed is an Atom feed of the posts, dates are in RFC 3339.
+type atomFeed struct {
+	XMLName xml.Name   `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string     `xml:"title"`
+	ID      string     `xml:"id"`
+	Link    []atomLink `xml:"link"`
+	Updated string     `xml:"updated"`
+	// Author is the author of the entries without one of their own, Atom
+	// requires every entry to have an author.
+	Author  string      `xml:"author>name"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string   `xml:"title"`
+	ID        string   `xml:"id"`
+	Link      atomLink `xml:"link"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+	Author    string   `xml:"author>name,omitempty"`
+	Summary   string   `xml:"summary,omitempty"`
+}
+
+// sitemap is the urlset of a sitemap.xml.
+type sitemap struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// SetSite sets the title, the base URL, such as `https://hackcv.com`, and
+// the author of the site, which the feed and the sitemap need. The title
+// is the author if author is empty.
+func (r *Render) SetSite(title, url, author string) {
+	if author == "" {
+		author = title
+	}
+	r.title = title
+	r.baseURL = strings.TrimSuffix(url, "/")
+	r.author = author
+}
+
+// link is the absolute URL of path.
+func (r *Render) link(path string) string {
+	return r.baseURL + "/" + path
+}
+
+// updated is the last time any of the posts changed, or the time of the
+// build if there is none.
+func (r *Render) updated() time.Time {
+	updated := time.Time{}
+	for _, v := range r.posts {
+		if v.Modified().After(updated) {
+			updated = v.Modified()
+		}
+	}
+	if updated.IsZero() {
+		return r.now.In(Location)
+	}
+
+	return updated
+}
+
+// ToFeed writes the Atom feed of the listed posts to atom.xml.
+func (r *Render) ToFeed() error {
+	f, err := r.outputFile("atom.xml")
+	if err != nil {
+		return err
+	}
+
+	feed := atomFeed{
+		Title:   r.title,
+		ID:      r.link(""),
+		Link:    []atomLink{{Href: r.link("")}, {Href: r.link("atom.xml"), Rel: "self"}},
+		Updated: r.updated().Format(time.RFC3339),
+		Author:  r.author,
+	}
+	for _, v := range r.posts {
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:     html.UnescapeString(string(v.Title)),
+			ID:        r.link(v.URL),
+			Link:      atomLink{Href: r.link(v.URL)},
+			Published: v.DateRFC3339(),
+			Updated:   v.UpdatedRFC3339(),
+			Author:    v.Author,
+			Summary:   v.ExcerptText,
+		})
+	}
+
+	return writeXML(f, feed)
+}
+
+// ToSitemap writes the index and the listed posts to sitemap.xml, with
+// the time of their last change.
+func (r *Render) ToSitemap() error {
+	f, err := r.outputFile("sitemap.xml")
+	if err != nil {
+		return err
+	}
+
+	urls := sitemap{URLs: []sitemapURL{{Loc: r.link(""), LastMod: r.updated().Format(time.RFC3339)}}}
+	for _, v := range r.posts {
+		urls.URLs = append(urls.URLs, sitemapURL{Loc: r.link(v.URL), LastMod: v.UpdatedRFC3339()})
+	}
+
+	return writeXML(f, urls)
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
@@ -0,0 +1,72 @@
+package cvblog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFeedAndSitemap(t *testing.T) {
+	dir, err := os.MkdirTemp("", "feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := []string{
+		"Date: 2012-10-25 12:22\nUpdated: 2013-01-02 08:00\nTitle: A & B\nURL: first\n\nintro",
+		"Date: 2012-10-26 12:22\nTitle: private\nStatus: private\nURL: private\n\nbody",
+	}
+
+	articles := make([]*Article, len(input))
+	for i, v := range input {
+		articles[i] = NewArticle([]byte(v))
+	}
+
+	render := NewRender(articles, "")
+	render.SetOutputDir(dir)
+	render.SetSite("hackcv", "https://hackcv.com/", "")
+	if err := render.ToFeed(); err != nil {
+		t.Fatal(err)
+	}
+	if err := render.ToSitemap(); err != nil {
+		t.Fatal(err)
+	}
+
+	feed, err := os.ReadFile(filepath.Join(dir, "atom.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom">`,
+		`<title>hackcv</title>`,
+		`<updated>2013-01-02T08:00:00Z</updated>`,
+		`<author>
+    <name>hackcv</name>
+  </author>`,
+		`<title>A &amp; B</title>`,
+		`<link href="https://hackcv.com/first.html"></link>`,
+		`<published>2012-10-25T12:22:00Z</published>`,
+		`<summary>intro</summary>`,
+	} {
+		if !strings.Contains(string(feed), v) {
+			t.Fatalf("feed fail, [%s] vs [%s]", feed, v)
+		}
+	}
+	if strings.Contains(string(feed), "private") {
+		t.Fatalf("feed private fail, [%s]", feed)
+	}
+
+	urls, err := os.ReadFile(filepath.Join(dir, "sitemap.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := `<url>
+    <loc>https://hackcv.com/first.html</loc>
+    <lastmod>2013-01-02T08:00:00Z</lastmod>
+  </url>`
+	if !strings.Contains(string(urls), output) || strings.Contains(string(urls), "private") {
+		t.Fatalf("sitemap fail, [%s] vs [%s]", urls, output)
+	}
+}
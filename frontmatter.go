@@ -0,0 +1,615 @@
+package cvblog
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// frontMatterError is a syntax error of the front matter at line.
+type frontMatterError struct {
+	line int
+	msg  string
+}
+
+func (e *frontMatterError) Error() string {
+	return fmt.Sprintf("%d: %s", e.line, e.msg)
+}
+
+// splitFrontMatter splits input into the lines between a `---` or `+++`
+// line and the same closing line, and the body after them. The format is
+// '-' for YAML, '+' for TOML and 0 if input has no front matter.
+func splitFrontMatter(input []byte) (byte, [][]byte, []byte) {
+	lines := bytes.Split(input, []byte("\n"))
+	if len(lines) == 0 {
+		return 0, nil, input
+	}
+
+	fence := bytes.TrimRight(lines[0], " \t\r")
+	if !bytes.Equal(fence, []byte("---")) && !bytes.Equal(fence, []byte("+++")) {
+		return 0, nil, input
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if bytes.Equal(bytes.TrimRight(lines[i], " \t\r"), fence) {
+			return fence[0], lines[1:i], bytes.Join(lines[i+1:], []byte("\n"))
+		}
+	}
+
+	return 0, nil, input
+}
+
+// parseYAML parses the subset of YAML used by front matter: `key: value`
+// pairs whose value is a scalar, a `[a, b]` list, a block list of `- item`
+// lines, a `|` or `>` block of text, or a map of such pairs indented below
+// the key. The line of every top level key is returned with the values.
+func parseYAML(lines [][]byte) (map[string]interface{}, map[string]int, error) {
+	params := make(map[string]interface{})
+	keys := make(map[string]int)
+	i, err := yamlMap(lines, 0, 0, params, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	if i < len(lines) {
+		return nil, nil, &frontMatterError{line: i + 2, msg: "unexpected indentation"}
+	}
+
+	return params, keys, nil
+}
+
+// yamlMap parses the pairs indented by indent from lines[i] into params,
+// it returns the index of the first line which is not part of the map. The
+// line of every key is put into keys unless it is nil.
+func yamlMap(lines [][]byte, i, indent int, params map[string]interface{}, keys map[string]int) (int, error) {
+	for i < len(lines) {
+		text := string(bytes.TrimRight(lines[i], " \t\r"))
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			i++
+			continue
+		}
+		if width := len(text) - len(trimmed); width < indent {
+			return i, nil
+		} else if width > indent {
+			return i, &frontMatterError{line: i + 2, msg: "unexpected indentation"}
+		}
+
+		colon := strings.Index(trimmed, ":")
+		if colon <= 0 || colon+1 < len(trimmed) && trimmed[colon+1] != ' ' {
+			return i, &frontMatterError{line: i + 2, msg: fmt.Sprintf("%q is not a key: value pair", trimmed)}
+		}
+		key := unquoteKey(strings.TrimSpace(trimmed[:colon]))
+		value := stripComment(strings.TrimSpace(trimmed[colon+1:]))
+		line := i + 2
+		i++
+		if _, exist := params[key]; exist {
+			return i, &frontMatterError{line: line, msg: fmt.Sprintf("duplicate key %s", key)}
+		}
+		if keys != nil {
+			keys[key] = line
+		}
+
+		switch {
+		case value == "|" || value == ">":
+			var block []string
+			for ; i < len(lines); i++ {
+				text := string(bytes.TrimRight(lines[i], " \t\r"))
+				if strings.TrimSpace(text) != "" && len(text)-len(strings.TrimLeft(text, " ")) <= indent {
+					break
+				}
+				block = append(block, strings.TrimSpace(text))
+			}
+			sep := "\n"
+			if value == ">" {
+				sep = " "
+			}
+			params[key] = strings.TrimSpace(strings.Join(block, sep))
+
+		case value == "":
+			next := nextYAMLLine(lines, i)
+			if next >= len(lines) {
+				params[key] = nil
+				continue
+			}
+			text := string(lines[next])
+			width := len(text) - len(strings.TrimLeft(text, " "))
+			switch {
+			case width >= indent && (strings.HasPrefix(strings.TrimSpace(text), "- ") || strings.TrimSpace(text) == "-"):
+				list, end, err := yamlList(lines, next, width)
+				if err != nil {
+					return end, err
+				}
+				params[key], i = list, end
+			case width > indent:
+				child := make(map[string]interface{})
+				end, err := yamlMap(lines, next, width, child, nil)
+				if err != nil {
+					return end, err
+				}
+				params[key], i = child, end
+			default:
+				params[key] = nil
+			}
+
+		default:
+			v, err := yamlValue(value)
+			if err != nil {
+				return i, &frontMatterError{line: line, msg: fmt.Sprintf("%s: %v", key, err)}
+			}
+			params[key] = v
+		}
+	}
+
+	return i, nil
+}
+
+// yamlList parses the `- item` lines indented by indent from lines[i].
+func yamlList(lines [][]byte, i, indent int) ([]interface{}, int, error) {
+	var list []interface{}
+	for i = nextYAMLLine(lines, i); i < len(lines); i = nextYAMLLine(lines, i+1) {
+		text := string(bytes.TrimRight(lines[i], " \t\r"))
+		trimmed := strings.TrimLeft(text, " ")
+		if len(text)-len(trimmed) != indent || !strings.HasPrefix(trimmed, "-") {
+			break
+		}
+
+		v, err := yamlValue(stripComment(strings.TrimSpace(trimmed[1:])))
+		if err != nil {
+			return nil, i, &frontMatterError{line: i + 2, msg: err.Error()}
+		}
+		list = append(list, v)
+	}
+
+	return list, i, nil
+}
+
+// nextYAMLLine is the index of the first line from i which is neither
+// blank nor a comment.
+func nextYAMLLine(lines [][]byte, i int) int {
+	for i < len(lines) {
+		trimmed := bytes.TrimSpace(lines[i])
+		if len(trimmed) > 0 && trimmed[0] != '#' {
+			break
+		}
+		i++
+	}
+
+	return i
+}
+
+// yamlValue parses a flow scalar or a `[a, b]` list, plain scalars are
+// booleans, numbers, dates or strings.
+func yamlValue(value string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(value, "["):
+		if !strings.HasSuffix(value, "]") {
+			return nil, fmt.Errorf("unterminated list %s", value)
+		}
+		return parseList(value[1:len(value)-1], yamlValue)
+
+	case strings.HasPrefix(value, "{"):
+		return nil, fmt.Errorf("inline maps are not supported")
+
+	case strings.HasPrefix(value, "\""), strings.HasPrefix(value, "'"):
+		return unquoteValue(value)
+	}
+
+	switch value {
+	case "", "~", "null":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+
+	return plainValue(value), nil
+}
+
+// parseTOML parses the subset of TOML used by front matter: `key = value`
+// pairs, whose values may be strings, numbers, booleans, dates and arrays,
+// and `[table]` headers, which start a map of the following pairs. The line
+// of every top level key is returned with the values.
+func parseTOML(lines [][]byte) (map[string]interface{}, map[string]int, error) {
+	params := make(map[string]interface{})
+	keys := make(map[string]int)
+	table, top := params, true
+	for i := 0; i < len(lines); i++ {
+		line := i + 2
+		text := stripComment(strings.TrimSpace(string(lines[i])))
+		if text == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			if strings.HasPrefix(text, "[[") || !strings.HasSuffix(text, "]") {
+				return nil, nil, &frontMatterError{line: line, msg: fmt.Sprintf("unsupported table %s", text)}
+			}
+			name := unquoteKey(strings.TrimSpace(text[1 : len(text)-1]))
+			if _, exist := params[name]; exist {
+				return nil, nil, &frontMatterError{line: line, msg: fmt.Sprintf("duplicate table %s", name)}
+			}
+			table, top = make(map[string]interface{}), false
+			params[name] = table
+			keys[name] = line
+			continue
+		}
+
+		eq := strings.Index(text, "=")
+		if eq <= 0 {
+			return nil, nil, &frontMatterError{line: line, msg: fmt.Sprintf("%q is not a key = value pair", text)}
+		}
+		key := unquoteKey(strings.TrimSpace(text[:eq]))
+		value := strings.TrimSpace(text[eq+1:])
+
+		// an array may go on over the next lines
+		for strings.HasPrefix(value, "[") && strings.Count(value, "[") > strings.Count(value, "]") && i+1 < len(lines) {
+			i++
+			value += " " + stripComment(strings.TrimSpace(string(lines[i])))
+		}
+
+		if _, exist := table[key]; exist {
+			return nil, nil, &frontMatterError{line: line, msg: fmt.Sprintf("duplicate key %s", key)}
+		}
+		v, err := tomlValue(value)
+		if err != nil {
+			return nil, nil, &frontMatterError{line: line, msg: fmt.Sprintf("%s: %v", key, err)}
+		}
+		table[key] = v
+		if top {
+			keys[key] = line
+		}
+	}
+
+	return params, keys, nil
+}
+
+func tomlValue(value string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(value, "["):
+		if !strings.HasSuffix(value, "]") {
+			return nil, fmt.Errorf("unterminated array %s", value)
+		}
+		return parseList(strings.TrimSuffix(strings.TrimSpace(value[1:len(value)-1]), ","), tomlValue)
+
+	case strings.HasPrefix(value, "\"\"\""), strings.HasPrefix(value, "'''"):
+		return nil, fmt.Errorf("multi-line strings are not supported")
+
+	case strings.HasPrefix(value, "\""), strings.HasPrefix(value, "'"):
+		return unquoteValue(value)
+
+	case value == "true":
+		return true, nil
+
+	case value == "false":
+		return false, nil
+	}
+
+	v := plainValue(value)
+	if _, ok := v.(string); ok {
+		return nil, fmt.Errorf("invalid value %s", value)
+	}
+
+	return v, nil
+}
+
+// parseList splits input at the commas outside of quotes and brackets,
+// and parses every item with parse.
+func parseList(input string, parse func(string) (interface{}, error)) ([]interface{}, error) {
+	list := []interface{}{}
+	depth, quote, start := 0, byte(0), 0
+	for i := 0; i <= len(input); i++ {
+		if i < len(input) {
+			switch c := input[i]; {
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				} else if c == '\\' && quote == '"' {
+					i++
+				}
+				continue
+			case c == '"' || c == '\'':
+				quote = c
+				continue
+			case c == '[':
+				depth++
+				continue
+			case c == ']':
+				depth--
+				continue
+			case c != ',' || depth > 0:
+				continue
+			}
+		}
+
+		item := strings.TrimSpace(input[start:i])
+		start = i + 1
+		if item == "" {
+			if i < len(input) {
+				return nil, fmt.Errorf("empty item in [%s]", input)
+			}
+			continue
+		}
+		v, err := parse(item)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+
+	return list, nil
+}
+
+// plainValue is value as an integer, a float, a date or else a string.
+func plainValue(value string) interface{} {
+	if n, err := strconv.ParseInt(strings.Replace(value, "_", "", -1), 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	if t, err := parseDate(value); err == nil {
+		return t
+	}
+
+	return value
+}
+
+// unquoteValue parses a "double" quoted string with escapes or a 'single'
+// quoted one, which is taken as it is but for YAML's doubled quotes.
+func unquoteValue(value string) (interface{}, error) {
+	if value[0] == '"' {
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", value)
+		}
+		return s, nil
+	}
+
+	if len(value) < 2 || value[len(value)-1] != '\'' {
+		return nil, fmt.Errorf("invalid string %s", value)
+	}
+
+	return strings.Replace(value[1:len(value)-1], "''", "'", -1), nil
+}
+
+func unquoteKey(key string) string {
+	if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') && key[len(key)-1] == key[0] {
+		return key[1 : len(key)-1]
+	}
+
+	return key
+}
+
+// stripComment removes a ` # comment` outside of quotes from the end of
+// value.
+func stripComment(value string) string {
+	quote := byte(0)
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || value[i-1] == ' ' || value[i-1] == '\t'):
+			return strings.TrimSpace(value[:i])
+		}
+	}
+
+	return value
+}
+
+// parseFrontMatter sets the fields of the article from the front matter
+// lines, the other keys go into Params. Syntax errors and values of the
+// wrong type are reported in the diagnostics of h.
+func (a *Article) parseFrontMatter(format byte, lines [][]byte, h *header) {
+	parse := parseYAML
+	if format == '+' {
+		parse = parseTOML
+	}
+
+	params, keys, err := parse(lines)
+	if err != nil {
+		e := err.(*frontMatterError)
+		h.report(e.line, "", e.msg)
+		return
+	}
+
+	// the keys are set in the order of their lines, a key which names the
+	// same field as a key before it is reported and left out
+	names := make([]string, 0, len(params))
+	for key := range params {
+		names = append(names, key)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if keys[names[i]] != keys[names[j]] {
+			return keys[names[i]] < keys[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	for _, key := range names {
+		field := fieldName(key)
+		if line, exist := h.lines[field]; exist {
+			h.report(keys[key], field, fmt.Sprintf("%s is given again, the value of line %d is used", key, line))
+			continue
+		}
+		h.lines[field] = keys[key]
+		if err := a.setParam(field, params[key]); err != nil {
+			h.report(keys[key], field, err.Error())
+		}
+	}
+}
+
+// fieldAliases maps the keys used by other site generators to the fields
+// of the article.
+var fieldAliases = map[string]string{
+	"lastmod":    "updated",
+	"categories": "category",
+	"slug":       "url",
+	"summary":    "description",
+	"image":      "cover",
+}
+
+// fields are the front matter keys which are fields of the article.
+var fields = map[string]bool{
+	"title": true, "date": true, "updated": true, "category": true,
+	"tags": true, "draft": true, "url": true, "description": true,
+	"author": true, "cover": true, "status": true,
+}
+
+// fieldName is the field of the article named by the front matter key, or
+// key itself if it is not a field.
+func fieldName(key string) string {
+	lower := strings.ToLower(key)
+	if field, exist := fieldAliases[lower]; exist {
+		return field
+	}
+	if fields[lower] {
+		return lower
+	}
+
+	return key
+}
+
+// setParam sets the field of the article named by fieldName to value, a
+// key which is not a field is kept in Params.
+func (a *Article) setParam(key string, value interface{}) error {
+	switch key {
+	case "title":
+		title, err := stringParam(value)
+		a.Title = template.HTML(template.HTMLEscapeString(title))
+		return err
+
+	case "date":
+		t, err := timeParam(value)
+		if err != nil {
+			return err
+		}
+		a.setDate(t)
+
+	case "updated":
+		t, err := timeParam(value)
+		if err != nil {
+			return err
+		}
+		a.Updated = t.In(Location)
+
+	case "category":
+		// the first one of a list of categories is used
+		if list, ok := value.([]interface{}); ok && len(list) > 0 {
+			value = list[0]
+		}
+		category, err := stringParam(value)
+		if err != nil {
+			return err
+		}
+		if category != "" {
+			a.Category = category
+		}
+
+	case "tags":
+		tags, err := listParam(value)
+		a.Tags = tags
+		return err
+
+	case "draft":
+		draft, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("%v is not true or false", value)
+		}
+		a.Draft = draft
+
+	case "url":
+		slug, err := stringParam(value)
+		if err != nil {
+			return err
+		}
+		a.setSlug(slug)
+
+	case "description":
+		description, err := stringParam(value)
+		a.Description = description
+		return err
+
+	case "author":
+		author, err := stringParam(value)
+		a.Author = author
+		return err
+
+	case "cover":
+		cover, err := stringParam(value)
+		a.Cover = cover
+		return err
+
+	case "status":
+		status, err := stringParam(value)
+		a.Status = status
+		return err
+
+	default:
+		a.Params[key] = value
+	}
+
+	return nil
+}
+
+// stringParam is value as a string, numbers and dates are formatted.
+func stringParam(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", nil
+	case []interface{}, map[string]interface{}:
+		return "", fmt.Errorf("a list or map is not a string")
+	case time.Time:
+		return v.Format("2006-01-02"), nil
+	}
+
+	return fmt.Sprint(value), nil
+}
+
+// listParam is a list of strings, or a string of comma separated items.
+func listParam(value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		s, err := stringParam(value)
+		if err != nil || s == "" {
+			return nil, err
+		}
+		for _, v := range strings.Split(s, ",") {
+			list = append(list, strings.TrimSpace(v))
+		}
+	}
+
+	result := []string{}
+	for _, v := range list {
+		s, err := stringParam(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+
+	return result, nil
+}
+
+func timeParam(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return parseDate(v)
+	}
+
+	return time.Time{}, fmt.Errorf("%v is not a date", value)
+}
@@ -0,0 +1,133 @@
+package cvblog
+
+import (
+	"bytes"
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseYAML(t *testing.T) {
+	input := "title: \"Hello: world\" # comment\ndate: 2024-03-01 08:30\ntags: [go, 'it''s']\nseries:\n  - one\n  - 2\nextra:\n  key: value\n  flag: true\ndescription: >\n  folded\n  text\nempty:\n"
+	params, keys, err := parseYAML(bytes.Split([]byte(input), []byte("\n")))
+	if err != nil {
+		t.Fatalf("parse yaml fail, %v", err)
+	}
+
+	output := map[string]interface{}{
+		"title":       "Hello: world",
+		"date":        time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC),
+		"tags":        []interface{}{"go", "it's"},
+		"series":      []interface{}{"one", int64(2)},
+		"extra":       map[string]interface{}{"key": "value", "flag": true},
+		"description": "folded text",
+		"empty":       nil,
+	}
+	if !reflect.DeepEqual(params, output) {
+		t.Fatalf("parse yaml fail, %#v vs %#v", params, output)
+	}
+	if keys["tags"] != 4 || keys["extra"] != 8 {
+		t.Fatalf("parse yaml lines fail, %v", keys)
+	}
+
+	for _, v := range []string{"title: a\n  bad: indent", "no pair", "a: 1\na: 2", "a: [1, 2"} {
+		if _, _, err := parseYAML(bytes.Split([]byte(v), []byte("\n"))); err == nil {
+			t.Fatalf("parse yaml error fail, [%s]", v)
+		}
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	input := "title = 'Hello' # comment\ndate = 2024-03-01T08:30:00+08:00\ndraft = false\ntags = [\n  \"go\",\n  \"toml\",\n]\n\n[extra]\nweight = 1.5\n"
+	params, keys, err := parseTOML(bytes.Split([]byte(input), []byte("\n")))
+	if err != nil {
+		t.Fatalf("parse toml fail, %v", err)
+	}
+
+	output := map[string]interface{}{
+		"title": "Hello",
+		"date":  time.Date(2024, 3, 1, 8, 30, 0, 0, time.FixedZone("", 8*3600)),
+		"draft": false,
+		"tags":  []interface{}{"go", "toml"},
+		"extra": map[string]interface{}{"weight": 1.5},
+	}
+	if params["date"].(time.Time).Equal(output["date"].(time.Time)) {
+		params["date"] = output["date"]
+	}
+	if !reflect.DeepEqual(params, output) {
+		t.Fatalf("parse toml fail, %#v vs %#v", params, output)
+	}
+	if keys["extra"] != 10 || keys["weight"] != 0 {
+		t.Fatalf("parse toml lines fail, %v", keys)
+	}
+
+	for _, v := range []string{"title = bare", "[[array]]", "a = 1\na = 2", "a = \"\"\"x\"\"\""} {
+		if _, _, err := parseTOML(bytes.Split([]byte(v), []byte("\n"))); err == nil {
+			t.Fatalf("parse toml error fail, [%s]", v)
+		}
+	}
+}
+
+func TestArticleFrontMatter(t *testing.T) {
+	input := []string{
+		"---\ntitle: 前言 & more\ndate: 2024-03-01\nupdated: 2024-03-02T10:00:00+08:00\ncategory: 技术\ntags:\n  - go\n  - blog\ndraft: true\nslug: /hello-world/\ndescription: About\nauthor: cvley\ncover: /img/cover.png\nseries: intro\n---\n\n正文",
+		"+++\ntitle = \"TOML\"\ndate = 2024-03-01\ncategories = [\"Notes\"]\ntags = \"a, b\"\nurl = \"toml.html\"\n\n[extra]\nlang = \"en\"\n+++\n正文",
+		"Date: 2012-10-25 12:22\nTitle: 旧格式\nCategory: 随笔\nURL: legacy\n\n正文",
+	}
+
+	for i, v := range input {
+		paper := NewArticle([]byte(v))
+		if len(paper.Warnings) > 0 || !strings.Contains(string(paper.Body), "正文") {
+			t.Fatalf("article front matter %d fail, %v %s", i, paper.Warnings, paper.Body)
+		}
+
+		switch i {
+		case 0:
+			if paper.Title != "前言 &amp; more" || paper.Date != "2024-03-01 00:00" || paper.Updated.IsZero() ||
+				paper.Category != "技术" || !reflect.DeepEqual(paper.Tags, []string{"go", "blog"}) || !paper.Draft ||
+				paper.URL != "hello-world.html" || paper.Description != "About" || paper.Author != "cvley" ||
+				paper.Cover != "/img/cover.png" || paper.Params["series"] != "intro" {
+				t.Fatalf("article yaml fail, %+v", paper)
+			}
+		case 1:
+			if paper.Title != "TOML" || paper.Category != "Notes" || !reflect.DeepEqual(paper.Tags, []string{"a", "b"}) ||
+				paper.URL != "toml.html" || paper.Params["extra"].(map[string]interface{})["lang"] != "en" {
+				t.Fatalf("article toml fail, %+v", paper)
+			}
+		case 2:
+			if paper.Title != "旧格式" || paper.Category != "随笔" || paper.URL != "legacy.html" {
+				t.Fatalf("article legacy fail, %+v", paper)
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	tmpl := template.Must(template.New("params").Parse("{{.Params.series}}"))
+	if err := tmpl.Execute(&buffer, NewArticle([]byte(input[0]))); err != nil || buffer.String() != "intro" {
+		t.Fatalf("article params fail, %v %s", err, buffer.String())
+	}
+
+	paper := NewArticle([]byte("---\ntitle: x\ndate: someday\ndraft: maybe\nslug: x\n---\nbody"))
+	if len(paper.Warnings) != 2 || paper.Warnings[0].Line != 3 || paper.Warnings[1].Line != 4 {
+		t.Fatalf("article front matter warnings fail, %v", paper.Warnings)
+	}
+}
+
+func TestFrontMatterAliases(t *testing.T) {
+	input := "---\ntitle: x\ndate: 2024-03-01\nslug: from-slug\nurl: from-url\ncategories: [A]\ncategory: B\nTitle: y\n---\nbody"
+	output := "a.md:5: url: url is given again, the value of line 4 is used\n" +
+		"a.md:7: category: category is given again, the value of line 6 is used\n" +
+		"a.md:8: title: Title is given again, the value of line 2 is used"
+
+	for i := 0; i < 50; i++ {
+		paper, err := ParseArticle("a.md", []byte(input))
+		if paper.URL != "from-slug.html" || paper.Category != "A" || paper.Title != "x" {
+			t.Fatalf("front matter aliases fail, %s %s %s", paper.URL, paper.Category, paper.Title)
+		}
+		if err == nil || err.Error() != output {
+			t.Fatalf("front matter aliases fail, [%v] vs [%s]", err, output)
+		}
+	}
+}
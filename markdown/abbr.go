@@ -0,0 +1,163 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+var reAbbreviation *regexp.Regexp
+
+func init() {
+	reAbbreviation = regexp.MustCompile(`^ {0,3}\*\[([^\]]+)\]:[ \t]*(.*)$`)
+}
+
+func (p *blockParser) isAbbreviationDefinition(i int) bool {
+	return p.ext != nil && p.ext.extensions&Abbreviations != 0 && reAbbreviation.Match(p.lines[i].text)
+}
+
+func (p *blockParser) parseAbbreviationDefinition() Node {
+	l := p.peek()
+	p.pos++
+
+	ret := reAbbreviation.FindSubmatch(l.text)
+	return &AbbreviationDefinition{
+		Position: Position{Line: l.num, Column: l.col + len(leadingSpace(l.text))},
+		Label:    string(bytes.TrimSpace(ret[1])),
+		Title:    string(bytes.TrimSpace(ret[2])),
+	}
+}
+
+// abbreviate wraps every defined word in the text of doc into an
+// Abbreviation, the first definition of a label wins and one with an empty
+// title is ignored.
+func abbreviate(doc *Document) {
+	titles := make(map[string]string)
+	Walk(doc, func(node Node) bool {
+		if def, ok := node.(*AbbreviationDefinition); ok {
+			if _, exist := titles[def.Label]; !exist {
+				titles[def.Label] = def.Title
+			}
+		}
+		return true
+	})
+
+	var labels []string
+	for label, title := range titles {
+		if title != "" {
+			labels = append(labels, label)
+		}
+	}
+	if len(labels) == 0 {
+		return
+	}
+
+	// the longest label is tried first, so `HTML5` wins over `HTML`
+	sort.Slice(labels, func(i, j int) bool {
+		if len(labels[i]) != len(labels[j]) {
+			return len(labels[i]) > len(labels[j])
+		}
+		return labels[i] < labels[j]
+	})
+	a := &abbreviator{labels: labels, titles: titles}
+
+	Walk(doc, func(node Node) bool {
+		switch n := node.(type) {
+		case *Paragraph:
+			n.Inlines = a.inlines(n.Inlines)
+		case *Heading:
+			n.Inlines = a.inlines(n.Inlines)
+		case *TableCell:
+			n.Inlines = a.inlines(n.Inlines)
+		case *DefinitionTerm:
+			n.Inlines = a.inlines(n.Inlines)
+		default:
+			return true
+		}
+		return false
+	})
+}
+
+type abbreviator struct {
+	labels []string
+	titles map[string]string
+}
+
+func (a *abbreviator) inlines(nodes []Node) []Node {
+	var result []Node
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *Text:
+			result = append(result, a.text(n)...)
+			continue
+
+		case *Emphasis:
+			n.Inlines = a.inlines(n.Inlines)
+		case *Strikethrough:
+			n.Inlines = a.inlines(n.Inlines)
+		case *Link:
+			n.Inlines = a.inlines(n.Inlines)
+		}
+		result = append(result, node)
+	}
+
+	return result
+}
+
+// text splits t at the labels which are not part of a longer word.
+func (a *abbreviator) text(t *Text) []Node {
+	var result []Node
+	start := 0
+	for i := 0; i < len(t.Text); i++ {
+		if i > 0 && isAlnum(t.Text[i-1]) {
+			continue
+		}
+
+		label := a.match(t.Text[i:])
+		if label == "" {
+			continue
+		}
+
+		if i > start {
+			result = append(result, &Text{Position: shift(t, start), Text: t.Text[start:i]})
+		}
+		result = append(result, &Abbreviation{Position: shift(t, i), Text: t.Text[i : i+len(label)], Title: a.titles[label]})
+		start = i + len(label)
+		i = start - 1
+	}
+
+	if start == 0 {
+		return []Node{t}
+	}
+	if start < len(t.Text) {
+		result = append(result, &Text{Position: shift(t, start), Text: t.Text[start:]})
+	}
+
+	return result
+}
+
+// match is the longest label at the start of text which ends a word.
+func (a *abbreviator) match(text []byte) string {
+	for _, label := range a.labels {
+		if !bytes.HasPrefix(text, []byte(label)) {
+			continue
+		}
+		if len(text) == len(label) || !isAlnum(text[len(label)]) {
+			return label
+		}
+	}
+
+	return ""
+}
+
+// shift is the position of t.Text[i], text nodes do not span lines.
+func shift(t *Text, i int) Position {
+	return Position{Line: t.Line, Column: t.Column + i}
+}
+
+func (r *htmlRenderer) renderAbbreviation(n *Abbreviation) {
+	r.buffer.WriteString(fmt.Sprintf("<abbr title=\"%s\">", escapeHTML([]byte(n.Title))))
+	r.writeText(n.Text)
+	r.buffer.WriteString("</abbr>")
+}
@@ -0,0 +1,30 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAbbreviation(t *testing.T) {
+	parser := NewParser()
+	parser.Enable(Abbreviations)
+
+	input := [][]byte{
+		[]byte("The HTML and HTML5 specs, not HTMLX.\n\n*[HTML]: Hyper Text Markup Language\n*[HTML5]: HTML version 5"),
+		[]byte("Use *W3C* [links](/w3c) and `W3C`.\n*[W3C]: World Wide Web \"Consortium\""),
+		[]byte("# About CSS\n\n*[CSS]: Cascading Style Sheets\n*[CSS]: ignored\n*[JS]:"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<p>The <abbr title=\"Hyper Text Markup Language\">HTML</abbr> and <abbr title=\"HTML version 5\">HTML5</abbr> specs, not HTMLX.</p>\n"),
+		[]byte("\n<p>Use <em><abbr title=\"World Wide Web &quot;Consortium&quot;\">W3C</abbr></em> <a href=\"/w3c\">links</a> and <code>W3C</code>.</p>\n"),
+		[]byte("\n<h1 id=\"about-css\"> About <abbr title=\"Cascading Style Sheets\">CSS</abbr> </h1>\n"),
+	}
+
+	for i, v := range input {
+		result := RenderHTML(parser.Parse(v))
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("Abbreviation fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	reAlert         *regexp.Regexp
+	reAdmonition    *regexp.Regexp
+	reAdmonitionEnd *regexp.Regexp
+)
+
+func init() {
+	reAlert = regexp.MustCompile(`(?i)^\[!(note|tip|important|warning|caution)\][ \t]*(.*)$`)
+	reAdmonition = regexp.MustCompile(`^ {0,3}:{3,}[ \t]*([a-zA-Z][a-zA-Z0-9_-]*)[ \t]*(.*)$`)
+	reAdmonitionEnd = regexp.MustCompile(`^ {0,3}:{3,}[ \t]*$`)
+}
+
+// parseAdmonition consumes a `:::kind title` block up to the matching `:::`
+// line, or up to the end of its container. Nested blocks are counted so
+// their closing lines do not end the outer one, and lines of fenced code
+// are not counted at all.
+func (p *blockParser) parseAdmonition() Node {
+	first := p.peek()
+	p.pos++
+
+	ret := reAdmonition.FindSubmatch(first.text)
+	block := &Admonition{
+		Position: Position{Line: first.num, Column: first.col + len(leadingSpace(first.text))},
+		Kind:     strings.ToLower(string(ret[1])),
+		Title:    string(bytes.TrimSpace(ret[2])),
+		Fenced:   true,
+	}
+
+	var lines []line
+	var fence []byte
+	depth := 0
+	for p.pos < len(p.lines) {
+		l := p.peek()
+		p.pos++
+		if fence != nil {
+			if isClosingFence(l.text, fence) {
+				fence = nil
+			}
+		} else if isFence(l.text) {
+			fence = reFence.FindSubmatch(l.text)[2]
+		} else if reAdmonition.Match(l.text) {
+			depth++
+		} else if reAdmonitionEnd.Match(l.text) {
+			if depth == 0 {
+				break
+			}
+			depth--
+		}
+		lines = append(lines, l)
+	}
+	block.Blocks = p.ext.parseBlocks(lines)
+
+	return block
+}
+
+// alert turns a quote whose first line is `[!NOTE]` or another GitHub alert
+// marker into an admonition, other quotes are returned as they are.
+func (p *blockParser) alert(quote *Quote, lines []line) Node {
+	if len(lines) == 0 {
+		return quote
+	}
+
+	ret := reAlert.FindSubmatch(bytes.TrimRight(lines[0].text, " \t"))
+	if ret == nil {
+		return quote
+	}
+
+	return &Admonition{
+		Position: quote.Position,
+		Kind:     strings.ToLower(string(ret[1])),
+		Title:    string(bytes.TrimSpace(ret[2])),
+		Blocks:   p.ext.parseBlocks(lines[1:]),
+	}
+}
+
+// admonitionTitle is the title of n, which defaults to its kind.
+func admonitionTitle(n *Admonition) string {
+	if n.Title != "" {
+		return n.Title
+	}
+
+	return strings.ToUpper(n.Kind[:1]) + n.Kind[1:]
+}
+
+func (r *htmlRenderer) renderAdmonition(n *Admonition) {
+	title := admonitionTitle(n)
+
+	tight := r.tight
+	r.tight = false
+	r.buffer.WriteString(fmt.Sprintf("\n<div class=\"admonition %s\">\n", escapeHTML([]byte(n.Kind))))
+	r.buffer.WriteString(fmt.Sprintf("<p class=\"admonition-title\">%s</p>\n", escapeHTML([]byte(title))))
+	r.renderBlocks(n.Blocks)
+	r.buffer.WriteString("</div>\n")
+	r.tight = tight
+}
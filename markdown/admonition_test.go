@@ -0,0 +1,34 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAdmonition(t *testing.T) {
+	input := [][]byte{
+		[]byte("> [!NOTE]\n> Some *text*.\n>\n> - a\n> - b"),
+		[]byte("> [!warning] Be careful\nlazy"),
+		[]byte(":::tip\nA tip.\n\n::::note Inner\nx\n:::\n\nafter\n:::\nout"),
+		[]byte("para\n:::danger\nx"),
+		[]byte("> [!TODO]\n> not an alert"),
+		[]byte(":::tip\n```text\n:::\n```\n:::\nout"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<div class=\"admonition note\">\n<p class=\"admonition-title\">Note</p>\n\n<p>Some <em>text</em>.</p>\n\n<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n</div>\n"),
+		[]byte("\n<div class=\"admonition warning\">\n<p class=\"admonition-title\">Be careful</p>\n\n<p>lazy</p>\n</div>\n"),
+		[]byte("\n<div class=\"admonition tip\">\n<p class=\"admonition-title\">Tip</p>\n\n<p>A tip.</p>\n\n" +
+			"<div class=\"admonition note\">\n<p class=\"admonition-title\">Inner</p>\n\n<p>x</p>\n</div>\n\n<p>after</p>\n</div>\n\n<p>out</p>\n"),
+		[]byte("\n<p>para</p>\n\n<div class=\"admonition danger\">\n<p class=\"admonition-title\">Danger</p>\n\n<p>x</p>\n</div>\n"),
+		[]byte("\n<blockquote>\n<p>[!TODO]\nnot an alert</p>\n</blockquote>\n"),
+		[]byte("\n<div class=\"admonition tip\">\n<p class=\"admonition-title\">Tip</p>\n\n<pre lang=\"text\">\n<code>\n:::\n</code>\n</pre>\n</div>\n\n<p>out</p>\n"),
+	}
+
+	for i, v := range input {
+		result := Render(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("Admonition fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package markdown
+
+// Position is the location of a node in the source, Line and Column are
+// both 1-based, Column counts bytes.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (pos Position) Pos() Position {
+	return pos
+}
+
+// Node is one element of the document tree returned by Parse.
+type Node interface {
+	Pos() Position
+	Children() []Node
+}
+
+type Align int
+
+const (
+	AlignNone Align = iota
+	AlignLeft
+	AlignCenter
+	AlignRight
+)
+
+func (a Align) String() string {
+	switch a {
+	case AlignLeft:
+		return "left"
+
+	case AlignCenter:
+		return "center"
+
+	case AlignRight:
+		return "right"
+
+	default:
+		return ""
+	}
+}
+
+// Document is the root of the tree.
+type Document struct {
+	Position
+	Blocks []Node
+}
+
+// Heading is an ATX heading such as `## Title`, Text is the raw inline
+// content and ID the slug used as its anchor.
+type Heading struct {
+	Position
+	Level   int
+	ID      string
+	Text    []byte
+	Inlines []Node
+}
+
+type Paragraph struct {
+	Position
+	Text    []byte
+	Inlines []Node
+}
+
+// Image is a paragraph made of a single image.
+type Image struct {
+	Position
+	Src   []byte
+	Alt   []byte
+	Title []byte
+}
+
+// CodeBlock is a fenced or indented code block, Lang is the first word of
+// the fence info string.
+type CodeBlock struct {
+	Position
+	Fenced bool
+	Info   string
+	Lang   string
+	Code   []byte
+}
+
+// Diagram is a fenced code block of a diagram language, mermaid or dot,
+// which is rendered to SVG if its syntax is supported.
+type Diagram struct {
+	Position
+	Lang   string
+	Source []byte
+}
+
+// ThematicBreak is a `---`, `***` or `___` line.
+type ThematicBreak struct {
+	Position
+}
+
+type Quote struct {
+	Position
+	Blocks []Node
+}
+
+// Admonition is a callout such as `> [!NOTE]` or a `:::tip` block, Kind
+// is lowercased and Title is empty unless it is given after the marker.
+type Admonition struct {
+	Position
+	Kind   string
+	Title  string
+	Fenced bool
+	Blocks []Node
+}
+
+// List is an ordered or unordered list, a tight list has no blank lines
+// between its items and renders them without paragraphs.
+type List struct {
+	Position
+	Ordered bool
+	Start   int
+	Tight   bool
+	Items   []*ListItem
+}
+
+// ListItem is an item of a list, a task item starts with `[ ]` or `[x]`
+// which is not part of its blocks.
+type ListItem struct {
+	Position
+	Task    bool
+	Checked bool
+	Blocks  []Node
+}
+
+// HTMLBlock is a block of raw HTML, it is rendered according to the HTML
+// mode of the options.
+type HTMLBlock struct {
+	Position
+	HTML []byte
+}
+
+// MathBlock is a `$$` display block, TeX is the source without the dollar
+// signs.
+type MathBlock struct {
+	Position
+	TeX []byte
+}
+
+// FootnoteDefinition is a `[^label]: text` definition, it is rendered in
+// the footnotes section at the end of the document.
+type FootnoteDefinition struct {
+	Position
+	Label  string
+	Blocks []Node
+}
+
+// LinkDefinition is a `[label]: url "title"` definition, which is used by
+// the reference links of the document and is not rendered.
+type LinkDefinition struct {
+	Position
+	Label string
+	URL   string
+	Title string
+}
+
+// DefinitionList is a list of `Term` lines each followed by `: definition`
+// lines, Items holds the terms and definitions in the order of the source.
+type DefinitionList struct {
+	Position
+	Items []Node
+}
+
+type DefinitionTerm struct {
+	Position
+	Text    []byte
+	Inlines []Node
+}
+
+// Definition is a `: definition` of a term, a loose definition follows a
+// blank line and renders its paragraphs with <p>.
+type Definition struct {
+	Position
+	Loose  bool
+	Blocks []Node
+}
+
+// AbbreviationDefinition is a `*[HTML]: Hyper Text Markup Language` line,
+// which marks every HTML of the document and is not rendered.
+type AbbreviationDefinition struct {
+	Position
+	Label string
+	Title string
+}
+
+// Table is a pipe table, the first row is the header.
+type Table struct {
+	Position
+	Aligns []Align
+	Rows   []*TableRow
+}
+
+type TableRow struct {
+	Position
+	Header bool
+	Cells  []*TableCell
+}
+
+type TableCell struct {
+	Position
+	Header  bool
+	Align   Align
+	Text    []byte
+	Inlines []Node
+}
+
+// Text is literal inline text, backslash escapes and entity references
+// are decoded.
+type Text struct {
+	Position
+	Text []byte
+}
+
+type CodeSpan struct {
+	Position
+	Code []byte
+}
+
+// Emphasis is rendered as <em> for Level 1 and as <strong> for Level 2.
+type Emphasis struct {
+	Position
+	Level   int
+	Inlines []Node
+}
+
+type Strikethrough struct {
+	Position
+	Inlines []Node
+}
+
+// Link is an inline, reference or automatic link.
+type Link struct {
+	Position
+	Dest    string
+	Title   string
+	Inlines []Node
+
+	// auto marks a bare url, which is unwrapped inside of other links
+	auto bool
+}
+
+// InlineImage is an image inside of a paragraph, its alt text is the plain
+// text of Inlines.
+type InlineImage struct {
+	Position
+	Dest    string
+	Title   string
+	Inlines []Node
+}
+
+// RawHTML is an inline HTML tag or comment, it is rendered according to
+// the HTML mode of the options.
+type RawHTML struct {
+	Position
+	HTML []byte
+}
+
+// Math is `$...$` inline math, or `$$...$$` display math inside of a
+// paragraph.
+type Math struct {
+	Position
+	Display bool
+	TeX     []byte
+}
+
+// Abbreviation is a word of the text which has an abbreviation
+// definition.
+type Abbreviation struct {
+	Position
+	Text  []byte
+	Title string
+}
+
+type FootnoteRef struct {
+	Position
+	Label string
+}
+
+// SoftBreak is a line ending inside of a paragraph.
+type SoftBreak struct {
+	Position
+}
+
+// HardBreak is a line ending after two spaces or a backslash, it is
+// rendered as <br>.
+type HardBreak struct {
+	Position
+}
+
+func (doc *Document) Children() []Node               { return doc.Blocks }
+func (h *Heading) Children() []Node                  { return h.Inlines }
+func (p *Paragraph) Children() []Node                { return p.Inlines }
+func (img *Image) Children() []Node                  { return nil }
+func (code *CodeBlock) Children() []Node             { return nil }
+func (d *Diagram) Children() []Node                  { return nil }
+func (hr *ThematicBreak) Children() []Node           { return nil }
+func (q *Quote) Children() []Node                    { return q.Blocks }
+func (a *Admonition) Children() []Node               { return a.Blocks }
+func (item *ListItem) Children() []Node              { return item.Blocks }
+func (html *HTMLBlock) Children() []Node             { return nil }
+func (math *MathBlock) Children() []Node             { return nil }
+func (def *FootnoteDefinition) Children() []Node     { return def.Blocks }
+func (def *LinkDefinition) Children() []Node         { return nil }
+func (l *DefinitionList) Children() []Node           { return l.Items }
+func (term *DefinitionTerm) Children() []Node        { return term.Inlines }
+func (def *Definition) Children() []Node             { return def.Blocks }
+func (def *AbbreviationDefinition) Children() []Node { return nil }
+func (cell *TableCell) Children() []Node             { return cell.Inlines }
+func (t *Text) Children() []Node                     { return nil }
+func (code *CodeSpan) Children() []Node              { return nil }
+func (em *Emphasis) Children() []Node                { return em.Inlines }
+func (del *Strikethrough) Children() []Node          { return del.Inlines }
+func (link *Link) Children() []Node                  { return link.Inlines }
+func (img *InlineImage) Children() []Node            { return img.Inlines }
+func (raw *RawHTML) Children() []Node                { return nil }
+func (math *Math) Children() []Node                  { return nil }
+func (abbr *Abbreviation) Children() []Node          { return nil }
+func (ref *FootnoteRef) Children() []Node            { return nil }
+func (br *SoftBreak) Children() []Node               { return nil }
+func (br *HardBreak) Children() []Node               { return nil }
+
+func (l *List) Children() []Node {
+	nodes := make([]Node, len(l.Items))
+	for i, item := range l.Items {
+		nodes[i] = item
+	}
+
+	return nodes
+}
+
+func (t *Table) Children() []Node {
+	nodes := make([]Node, len(t.Rows))
+	for i, row := range t.Rows {
+		nodes[i] = row
+	}
+
+	return nodes
+}
+
+func (row *TableRow) Children() []Node {
+	nodes := make([]Node, len(row.Cells))
+	for i, cell := range row.Cells {
+		nodes[i] = cell
+	}
+
+	return nodes
+}
+
+// Walk traverses the tree rooted at node in depth-first order, the
+// children of a node are skipped if fn returns false for it.
+func Walk(node Node, fn func(Node) bool) {
+	if !fn(node) {
+		return
+	}
+
+	for _, child := range node.Children() {
+		Walk(child, fn)
+	}
+}
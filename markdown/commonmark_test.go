@@ -0,0 +1,166 @@
+package markdown
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var commonmark = flag.Bool("commonmark", false, "print the CommonMark compliance summary")
+
+// specExample is one example of the CommonMark spec, the fixture is the
+// spec.json published with the spec.
+type specExample struct {
+	Markdown string `json:"markdown"`
+	HTML     string `json:"html"`
+	Example  int    `json:"example"`
+	Section  string `json:"section"`
+}
+
+var (
+	reSpecCode      = regexp.MustCompile(`<pre(?: lang="([^"]*)")?>\n<code>\n`)
+	reSpecCodeEnd   = regexp.MustCompile(`\n</code>\n</pre>`)
+	reSpecHeadingID = regexp.MustCompile(`<(h[1-6]) id="[^"]*">`)
+	reSpecToken     = regexp.MustCompile(`<[^>]*>|[^<]+`)
+	reSpecTagName   = regexp.MustCompile(`^</?([a-zA-Z0-9]+)`)
+	reSpecSpace     = regexp.MustCompile(`\s+`)
+	reSpecVoid      = regexp.MustCompile(`\s*/>`)
+)
+
+var specBlockTags = words(`address article aside blockquote body dd details div dl dt
+	figcaption figure footer h1 h2 h3 h4 h5 h6 header hr html li main nav ol p pre
+	section summary table tbody td tfoot th thead tr ul`)
+
+// normalizeHTML makes the output of the renderer comparable with the spec,
+// the way the normalizer of the spec's test runner does. Whitespace around
+// block tags is dropped, heading ids are ignored, `<br />` is `<br>`, and the legacy
+// `<pre lang="x">\n<code>\n` is the same as `<pre><code class="language-x">`.
+func normalizeHTML(input string) string {
+	input = reSpecCode.ReplaceAllStringFunc(input, func(s string) string {
+		if lang := reSpecCode.FindStringSubmatch(s)[1]; lang != "" {
+			return fmt.Sprintf("<pre><code class=\"language-%s\">", lang)
+		}
+		return "<pre><code>"
+	})
+	input = reSpecCodeEnd.ReplaceAllString(input, "\n</code></pre>")
+	input = strings.Replace(input, ">\n</code></pre>", "></code></pre>", -1)
+	input = reSpecHeadingID.ReplaceAllString(input, "<$1>")
+
+	var result string
+	var pre, trim bool
+	for _, token := range reSpecToken.FindAllString(input, -1) {
+		if token[0] != '<' {
+			if !pre {
+				token = reSpecSpace.ReplaceAllString(token, " ")
+				if trim {
+					token = strings.TrimLeft(token, " ")
+				}
+			}
+			result += token
+			trim = false
+			continue
+		}
+
+		token = reSpecVoid.ReplaceAllString(token, ">")
+		name := reSpecTagName.FindStringSubmatch(token)
+		trim = name != nil && specBlockTags[strings.ToLower(name[1])]
+		if trim {
+			if !pre {
+				result = strings.TrimRight(result, " ")
+			}
+			if strings.ToLower(name[1]) == "pre" {
+				pre = token[1] != '/'
+			}
+		}
+		result += token
+	}
+
+	return strings.TrimSpace(result)
+}
+
+func TestCommonMark(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/commonmark-0.31.2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var examples []specExample
+	if err := json.Unmarshal(b, &examples); err != nil {
+		t.Fatal(err)
+	}
+
+	type summary struct {
+		name   string
+		pass   int
+		total  int
+		failed []int
+	}
+	var sections []*summary
+	index := make(map[string]*summary)
+
+	var pass int
+	for _, ex := range examples {
+		s, ok := index[ex.Section]
+		if !ok {
+			s = &summary{name: ex.Section}
+			index[ex.Section] = s
+			sections = append(sections, s)
+		}
+		s.total++
+
+		result := normalizeHTML(string(RenderWithOptions([]byte(ex.Markdown), Options{HTML: HTMLUnsafe})))
+		expect := normalizeHTML(ex.HTML)
+		if result == expect {
+			s.pass++
+			pass++
+			if commonmarkFailures[ex.Example] {
+				t.Errorf("CommonMark example %d (%s) passes, remove it from commonmarkFailures", ex.Example, ex.Section)
+			}
+			continue
+		}
+
+		s.failed = append(s.failed, ex.Example)
+		if !commonmarkFailures[ex.Example] {
+			t.Errorf("CommonMark example %d (%s) fail, [%s] vs [%s]", ex.Example, ex.Section, result, expect)
+		}
+	}
+
+	if *commonmark {
+		fmt.Printf("CommonMark 0.31.2: %d of %d examples pass\n", pass, len(examples))
+		for _, s := range sections {
+			fmt.Printf("%-45s %3d/%3d", s.name, s.pass, s.total)
+			if len(s.failed) > 0 {
+				fmt.Printf("  failed: %v", s.failed)
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// commonmarkFailures are the examples known to fail, the block structure
+// the renderer does not support yet and the places where it deliberately
+// differs from the spec.
+var commonmarkFailures = map[int]bool{
+	// tabs are not expanded to columns after a container marker
+	5: true, 6: true, 7: true,
+
+	// the blank line between blocks shows inside of a raw <pre>
+	148: true,
+
+	// list children need only be two columns deeper than the marker, and
+	// `1.` and `1)` items make one list
+	257: true, 276: true, 297: true, 302: true, 311: true, 313: true,
+
+	// labels are matched by lowercase, not by Unicode case folding
+	540: true,
+
+	// a paragraph of a single image is rendered as a bare <img>
+	572: true, 578: true, 580: true, 581: true,
+
+	// bare urls are linked
+	608: true, 611: true,
+}
@@ -0,0 +1,165 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+)
+
+var reDefinition *regexp.Regexp
+
+func init() {
+	reDefinition = regexp.MustCompile(`^ {0,3}:[ \t]+`)
+}
+
+// isDefinitionList reports whether the lines from index i are terms
+// followed by a `: definition` line, with at most one blank line between.
+func (p *blockParser) isDefinitionList(i int) bool {
+	if p.ext == nil || p.ext.extensions&DefinitionLists == 0 {
+		return false
+	}
+
+	j := i
+	for j < len(p.lines) && !isBlank(p.lines[j].text) && !reDefinition.Match(p.lines[j].text) {
+		if j > i && p.interrupts(j) {
+			return false
+		}
+		j++
+	}
+	if j == i {
+		return false
+	}
+	if j < len(p.lines) && isBlank(p.lines[j].text) {
+		j++
+	}
+
+	return j < len(p.lines) && reDefinition.Match(p.lines[j].text)
+}
+
+// parseDefinitionList consumes groups of terms and their definitions, a
+// blank line before a definition makes it loose.
+func (p *blockParser) parseDefinitionList() Node {
+	first := p.peek()
+	list := &DefinitionList{Position: Position{Line: first.num, Column: first.col + len(leadingSpace(first.text))}}
+
+	for p.pos < len(p.lines) && p.isDefinitionList(p.pos) {
+		for l := p.peek(); !isBlank(l.text) && !reDefinition.Match(l.text); l = p.peek() {
+			list.Items = append(list.Items, &DefinitionTerm{
+				Position: Position{Line: l.num, Column: l.col + len(leadingSpace(l.text))},
+				Text:     bytes.TrimSpace(l.text),
+			})
+			p.pos++
+		}
+
+		loose := false
+		for p.pos < len(p.lines) {
+			if next := p.skipBlank(p.pos); next > p.pos {
+				if next >= len(p.lines) || !reDefinition.Match(p.lines[next].text) {
+					break
+				}
+				loose = true
+				p.pos = next
+			}
+			if !reDefinition.Match(p.peek().text) {
+				break
+			}
+			list.Items = append(list.Items, p.parseDefinition(loose))
+		}
+
+		if next := p.skipBlank(p.pos); next < len(p.lines) && p.isDefinitionList(next) {
+			p.pos = next
+		}
+	}
+
+	return list
+}
+
+// parseDefinition consumes a `: definition` with its indented or lazy
+// continuation lines, blank lines are kept if an indented line follows.
+func (p *blockParser) parseDefinition(loose bool) *Definition {
+	first := p.peek()
+	ret := reDefinition.FindIndex(first.text)
+	width := ret[1]
+	if width > 4 {
+		width = 4
+	}
+	p.pos++
+
+	lines := []line{{text: first.text[ret[1]:], num: first.num, col: first.col + ret[1]}}
+	var para openParagraph
+	for p.pos < len(p.lines) {
+		l := p.peek()
+		if isBlank(l.text) {
+			next := p.skipBlank(p.pos)
+			if next >= len(p.lines) || indentWidth(leadingSpace(p.lines[next].text)) < width {
+				break
+			}
+			for ; p.pos < next; p.pos++ {
+				lines = append(lines, line{num: p.peek().num, col: p.peek().col})
+			}
+			para.add(nil)
+			loose = true
+			continue
+		}
+
+		if reDefinition.Match(l.text) || p.isDefinitionList(p.pos) {
+			break
+		}
+		if space := leadingSpace(l.text); indentWidth(space) >= width {
+			skip := len(space)
+			if skip > width {
+				skip = width
+			}
+			lines = append(lines, line{text: l.text[skip:], num: l.num, col: l.col + skip})
+			para.add(lines[len(lines)-1].text)
+			p.pos++
+			continue
+		}
+
+		// lazy continuation of a paragraph of the definition
+		if p.interrupts(p.pos) || !para.accepts(p, lines) {
+			break
+		}
+		l.lazy = true
+		lines = append(lines, l)
+		p.pos++
+	}
+
+	return &Definition{
+		Position: Position{Line: first.num, Column: first.col + len(leadingSpace(first.text))},
+		Loose:    loose,
+		Blocks:   p.ext.parseBlocks(lines),
+	}
+}
+
+// skipBlank is the index of the first line from i which is not blank.
+func (p *blockParser) skipBlank(i int) int {
+	for i < len(p.lines) && isBlank(p.lines[i].text) {
+		i++
+	}
+
+	return i
+}
+
+func (r *htmlRenderer) renderDefinitionList(l *DefinitionList) {
+	r.buffer.WriteString("\n<dl>\n")
+
+	tight := r.tight
+	for _, item := range l.Items {
+		switch n := item.(type) {
+		case *DefinitionTerm:
+			r.resetTypography()
+			r.buffer.WriteString("<dt>")
+			r.renderBlocks(n.Inlines)
+			r.buffer.WriteString("</dt>\n")
+
+		case *Definition:
+			r.tight = !n.Loose
+			r.buffer.WriteString("<dd>")
+			r.renderBlocks(n.Blocks)
+			r.buffer.WriteString("</dd>\n")
+		}
+	}
+	r.tight = tight
+
+	r.buffer.WriteString("</dl>\n")
+}
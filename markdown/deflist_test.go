@@ -0,0 +1,39 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDefinitionList(t *testing.T) {
+	parser := NewParser()
+	parser.Enable(DefinitionLists)
+
+	input := [][]byte{
+		[]byte("Apple\n: A *fruit*.\n: A company."),
+		[]byte("Term 1\nTerm 2\n\n: Loose definition.\n\n    Second paragraph.\n\nOrange\n: Another\nlazy line."),
+		[]byte("`code`\n:   - a\n    - b"),
+		[]byte(": no term"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<dl>\n<dt>Apple</dt>\n<dd>A <em>fruit</em>.</dd>\n<dd>A company.</dd>\n</dl>\n"),
+		[]byte("\n<dl>\n<dt>Term 1</dt>\n<dt>Term 2</dt>\n<dd>\n<p>Loose definition.</p>\n\n<p>Second paragraph.</p>\n</dd>\n" +
+			"<dt>Orange</dt>\n<dd>Another\nlazy line.</dd>\n</dl>\n"),
+		[]byte("\n<dl>\n<dt><code>code</code></dt>\n<dd>\n<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n</dd>\n</dl>\n"),
+		[]byte("\n<p>: no term</p>\n"),
+	}
+
+	for i, v := range input {
+		result := RenderHTML(parser.Parse(v))
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("DefinitionList fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+
+	result := Render(input[0])
+	expect := []byte("\n<p>Apple\n: A <em>fruit</em>.\n: A company.</p>\n")
+	if !bytes.Equal(result, expect) {
+		t.Fatalf("DefinitionList disabled fail, [%s] vs [%s]", string(result), string(expect))
+	}
+}
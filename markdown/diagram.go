@@ -0,0 +1,542 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	reFlowchart    *regexp.Regexp
+	reParticipant  *regexp.Regexp
+	reMessage      *regexp.Regexp
+	reNote         *regexp.Regexp
+	reDotGraph     *regexp.Regexp
+	reDotAttribute *regexp.Regexp
+)
+
+func init() {
+	reFlowchart = regexp.MustCompile(`^(?:graph|flowchart)(?:[ \t]+(TD|TB|LR))?[ \t]*;?$`)
+	reParticipant = regexp.MustCompile(`^(?:participant|actor)[ \t]+([^ \t]+)(?:[ \t]+as[ \t]+(.+))?$`)
+	reMessage = regexp.MustCompile(`^([^ \t:>-]+)[ \t]*(-->>|->>|-->|->)[ \t]*([^ \t:+-][^ \t:]*)[ \t]*:(.*)$`)
+	reNote = regexp.MustCompile(`(?i)^note[ \t]+(left of|right of|over)[ \t]+([^ \t,:]+)(?:[ \t]*,[ \t]*([^ \t:]+))?[ \t]*:(.*)$`)
+	reDotGraph = regexp.MustCompile(`^(?:strict[ \t]+)?(di)?graph[ \t]*(?:"[^"]*"|\w+)?[ \t]*\{`)
+	reDotAttribute = regexp.MustCompile(`^(\w+)[ \t]*=[ \t]*("[^"]*"|[\w.]+)$`)
+}
+
+// errDiagram is returned for diagram syntax outside of the supported
+// subset, such a diagram is left to mermaid.js or shown as code.
+var errDiagram = errors.New("unsupported diagram")
+
+// flowchart is a graph of boxes joined by lines, vertical charts go from
+// top to bottom and the others from left to right.
+type flowchart struct {
+	vertical bool
+	nodes    []*flowNode
+	ids      map[string]*flowNode
+	edges    []*flowEdge
+}
+
+// flowNode is a box of a flowchart, shape is '[' for a rectangle, '(' for
+// a rounded box and '{' for a diamond.
+type flowNode struct {
+	id    string
+	label string
+	shape byte
+
+	rank  int
+	order int
+	x, y  int
+	w, h  int
+}
+
+type flowEdge struct {
+	from, to *flowNode
+	label    string
+	dashed   bool
+	arrow    bool
+}
+
+func newFlowchart() *flowchart {
+	return &flowchart{vertical: true, ids: make(map[string]*flowNode)}
+}
+
+// node is the node id, which is added with its id as label if it is new.
+func (f *flowchart) node(id string) *flowNode {
+	if n, ok := f.ids[id]; ok {
+		return n
+	}
+
+	n := &flowNode{id: id, label: id, shape: '['}
+	f.ids[id] = n
+	f.nodes = append(f.nodes, n)
+	return n
+}
+
+// sequence is a sequence diagram, steps are messages and notes from top
+// to bottom.
+type sequence struct {
+	actors []*actor
+	ids    map[string]*actor
+	steps  []*step
+}
+
+type actor struct {
+	id    string
+	label string
+	x, w  int
+}
+
+// step is a message from one actor to another, or a note if side is set,
+// which is "left of", "right of" or "over".
+type step struct {
+	from, to *actor
+	text     string
+	dashed   bool
+	arrow    bool
+	side     string
+}
+
+func (s *sequence) actor(id string) *actor {
+	if a, ok := s.ids[id]; ok {
+		return a
+	}
+
+	a := &actor{id: id, label: id}
+	s.ids[id] = a
+	s.actors = append(s.actors, a)
+	return a
+}
+
+// diagramSVG renders source written in lang to SVG, it fails with
+// errDiagram unless source is in the subset of the syntax which is
+// supported: mermaid flowcharts and sequence diagrams, and dot graphs of
+// nodes and edges.
+func diagramSVG(lang string, source []byte) ([]byte, error) {
+	switch lang {
+	case "mermaid":
+		lines := diagramLines(string(source), "%%")
+		if len(lines) == 0 {
+			return nil, errDiagram
+		}
+		if lines[0] == "sequenceDiagram" {
+			s, err := parseSequence(lines[1:])
+			if err != nil {
+				return nil, err
+			}
+			return s.svg(), nil
+		}
+		f, err := parseMermaidFlowchart(lines)
+		if err != nil {
+			return nil, err
+		}
+		return f.svg(), nil
+
+	case "dot":
+		f, err := parseDot(string(source))
+		if err != nil {
+			return nil, err
+		}
+		return f.svg(), nil
+	}
+
+	return nil, errDiagram
+}
+
+// diagramLines is the trimmed lines of source which are neither blank nor
+// comments.
+func diagramLines(source, comment string) []string {
+	var lines []string
+	for _, l := range strings.Split(source, "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" && !strings.HasPrefix(l, comment) {
+			lines = append(lines, l)
+		}
+	}
+
+	return lines
+}
+
+// parseMermaidFlowchart parses a `graph TD` chart of nodes such as `A`,
+// `A[box]`, `A(rounded)` and `A{diamond}` joined by `-->`, `---`, `-.->`
+// or `==>` links, which may have a `|label|`.
+func parseMermaidFlowchart(lines []string) (*flowchart, error) {
+	ret := reFlowchart.FindStringSubmatch(lines[0])
+	if ret == nil {
+		return nil, errDiagram
+	}
+
+	f := newFlowchart()
+	f.vertical = ret[1] != "LR"
+	for _, l := range lines[1:] {
+		for _, stmt := range splitStatements(l) {
+			if err := f.parseChain(stmt); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(f.nodes) == 0 {
+		return nil, errDiagram
+	}
+
+	return f, nil
+}
+
+// parseChain parses `A --> B --> C`, a single node is a declaration.
+func (f *flowchart) parseChain(stmt string) error {
+	from, i, err := f.parseNode(stmt, 0)
+	if err != nil {
+		return err
+	}
+
+	for i = skipSpaces(stmt, i); i < len(stmt); i = skipSpaces(stmt, i) {
+		edge := &flowEdge{from: from}
+		n := 0
+		switch {
+		case strings.HasPrefix(stmt[i:], "-.->"):
+			edge.dashed, edge.arrow, n = true, true, 4
+		case strings.HasPrefix(stmt[i:], "-.-"):
+			edge.dashed, n = true, 3
+		case strings.HasPrefix(stmt[i:], "-->"), strings.HasPrefix(stmt[i:], "==>"):
+			edge.arrow, n = true, 3
+		case strings.HasPrefix(stmt[i:], "---"):
+			n = 3
+		default:
+			return errDiagram
+		}
+
+		i = skipSpaces(stmt, i+n)
+		if i < len(stmt) && stmt[i] == '|' {
+			end := strings.IndexByte(stmt[i+1:], '|')
+			if end < 0 {
+				return errDiagram
+			}
+			edge.label = unquote(strings.TrimSpace(stmt[i+1 : i+1+end]))
+			i = skipSpaces(stmt, i+end+2)
+		}
+
+		if edge.to, i, err = f.parseNode(stmt, i); err != nil {
+			return err
+		}
+		f.edges = append(f.edges, edge)
+		from = edge.to
+	}
+
+	return nil
+}
+
+// parseNode parses the node id at stmt[i] with an optional shape and
+// label.
+func (f *flowchart) parseNode(stmt string, i int) (*flowNode, int, error) {
+	start := i
+	for i < len(stmt) && (isAlnum(stmt[i]) || stmt[i] == '_') {
+		i++
+	}
+	if i == start {
+		return nil, 0, errDiagram
+	}
+	n := f.node(stmt[start:i])
+
+	if i < len(stmt) {
+		closing := map[byte]byte{'[': ']', '(': ')', '{': '}'}[stmt[i]]
+		if closing == 0 {
+			return n, i, nil
+		}
+		end := strings.IndexByte(stmt[i+1:], closing)
+		if end < 0 {
+			return nil, 0, errDiagram
+		}
+		label := strings.TrimSpace(stmt[i+1 : i+1+end])
+		if label == "" || strings.ContainsAny(label, "[](){}") {
+			return nil, 0, errDiagram
+		}
+		n.shape, n.label = stmt[i], unquote(label)
+		i += end + 2
+	}
+
+	return n, i, nil
+}
+
+// parseSequence parses the lines after `sequenceDiagram`: participants,
+// `A->>B: text` messages and notes.
+func parseSequence(lines []string) (*sequence, error) {
+	s := &sequence{ids: make(map[string]*actor)}
+	for _, l := range lines {
+		if ret := reParticipant.FindStringSubmatch(l); ret != nil {
+			a := s.actor(ret[1])
+			if ret[2] != "" {
+				a.label = strings.TrimSpace(ret[2])
+			}
+			continue
+		}
+
+		if ret := reMessage.FindStringSubmatch(l); ret != nil {
+			s.steps = append(s.steps, &step{
+				from:   s.actor(ret[1]),
+				to:     s.actor(ret[3]),
+				text:   strings.TrimSpace(ret[4]),
+				dashed: strings.HasPrefix(ret[2], "--"),
+				arrow:  strings.HasSuffix(ret[2], ">>"),
+			})
+			continue
+		}
+
+		if ret := reNote.FindStringSubmatch(l); ret != nil {
+			st := &step{from: s.actor(ret[2]), side: strings.ToLower(ret[1]), text: strings.TrimSpace(ret[4])}
+			st.to = st.from
+			if ret[3] != "" {
+				if st.side != "over" {
+					return nil, errDiagram
+				}
+				st.to = s.actor(ret[3])
+			}
+			s.steps = append(s.steps, st)
+			continue
+		}
+
+		return nil, errDiagram
+	}
+	if len(s.actors) == 0 {
+		return nil, errDiagram
+	}
+
+	return s, nil
+}
+
+// parseDot parses a graph or digraph of node and edge statements, the
+// attributes label, shape and style are used and rankdir=LR lays the graph
+// out from left to right.
+func parseDot(source string) (*flowchart, error) {
+	var lines []string
+	for _, l := range strings.Split(source, "\n") {
+		if i := strings.Index(l, "//"); i >= 0 && strings.Count(l[:i], "\"")%2 == 0 {
+			l = l[:i]
+		}
+		if !strings.HasPrefix(strings.TrimSpace(l), "#") {
+			lines = append(lines, l)
+		}
+	}
+	source = strings.TrimSpace(strings.Join(lines, "\n"))
+
+	ret := reDotGraph.FindStringSubmatchIndex(source)
+	if ret == nil || !strings.HasSuffix(source, "}") {
+		return nil, errDiagram
+	}
+	op := "--"
+	if ret[2] >= 0 {
+		op = "->"
+	}
+
+	f := newFlowchart()
+	for _, stmt := range splitStatements(strings.Replace(source[ret[1]:len(source)-1], "\n", ";", -1)) {
+		if err := f.parseDotStatement(stmt, op); err != nil {
+			return nil, err
+		}
+	}
+	if len(f.nodes) == 0 {
+		return nil, errDiagram
+	}
+
+	return f, nil
+}
+
+func (f *flowchart) parseDotStatement(stmt, op string) error {
+	if ret := reDotAttribute.FindStringSubmatch(stmt); ret != nil {
+		if ret[1] == "rankdir" {
+			f.vertical = unquote(ret[2]) != "LR"
+		}
+		return nil
+	}
+
+	attrs := ""
+	if i := strings.IndexByte(stmt, '['); i >= 0 {
+		if !strings.HasSuffix(stmt, "]") {
+			return errDiagram
+		}
+		stmt, attrs = strings.TrimSpace(stmt[:i]), stmt[i+1:len(stmt)-1]
+	}
+	params, err := dotAttributes(attrs)
+	if err != nil {
+		return err
+	}
+
+	switch stmt {
+	case "graph", "node", "edge":
+		return nil
+	}
+
+	ids := strings.Split(stmt, op)
+	for i, id := range ids {
+		if ids[i] = dotID(strings.TrimSpace(id)); ids[i] == "" {
+			return errDiagram
+		}
+	}
+
+	if len(ids) == 1 {
+		n := f.node(ids[0])
+		if label, ok := params["label"]; ok {
+			n.label = label
+		}
+		switch params["shape"] {
+		case "", "ellipse", "oval", "circle":
+			n.shape = '('
+		case "diamond":
+			n.shape = '{'
+		default:
+			n.shape = '['
+		}
+		return nil
+	}
+
+	for i := 1; i < len(ids); i++ {
+		f.edges = append(f.edges, &flowEdge{
+			from:   f.dotNode(ids[i-1]),
+			to:     f.dotNode(ids[i]),
+			label:  params["label"],
+			dashed: params["style"] == "dashed" || params["style"] == "dotted",
+			arrow:  op == "->",
+		})
+	}
+
+	return nil
+}
+
+// dotNode is the node id, a new node is an ellipse as in dot.
+func (f *flowchart) dotNode(id string) *flowNode {
+	_, exist := f.ids[id]
+	n := f.node(id)
+	if !exist {
+		n.shape = '('
+	}
+
+	return n
+}
+
+// dotID is the name of a quoted or a plain id, or "" if id is neither.
+func dotID(id string) string {
+	if len(id) >= 2 && id[0] == '"' && id[len(id)-1] == '"' && !strings.Contains(id[1:len(id)-1], "\"") {
+		return id[1 : len(id)-1]
+	}
+	for i := 0; i < len(id); i++ {
+		if !isAlnum(id[i]) && id[i] != '_' && id[i] != '.' {
+			return ""
+		}
+	}
+
+	return id
+}
+
+// dotAttributes parses `key=value` pairs separated by commas or spaces.
+func dotAttributes(input string) (map[string]string, error) {
+	params := make(map[string]string)
+	for i := skipSpaces(input, 0); i < len(input); i = skipSpaces(input, i) {
+		eq := strings.IndexByte(input[i:], '=')
+		if eq < 0 {
+			return nil, errDiagram
+		}
+		key := strings.TrimSpace(input[i : i+eq])
+		i = skipSpaces(input, i+eq+1)
+
+		end := i
+		if end < len(input) && input[end] == '"' {
+			quote := strings.IndexByte(input[end+1:], '"')
+			if quote < 0 {
+				return nil, errDiagram
+			}
+			end += quote + 2
+		} else {
+			for end < len(input) && !strings.ContainsRune(" \t,;", rune(input[end])) {
+				end++
+			}
+		}
+		params[key] = unquote(input[i:end])
+		i = end
+		if i < len(input) && (input[i] == ',' || input[i] == ';') {
+			i++
+		}
+	}
+
+	return params, nil
+}
+
+// splitStatements splits input at semicolons outside of quotes and
+// brackets.
+func splitStatements(input string) []string {
+	var stmts []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i <= len(input); i++ {
+		if i < len(input) {
+			switch c := input[i]; {
+			case c == '"':
+				quoted = !quoted
+			case quoted:
+			case c == '[' || c == '(' || c == '{':
+				depth++
+			case c == ']' || c == ')' || c == '}':
+				depth--
+			}
+			if input[i] != ';' || quoted || depth > 0 {
+				continue
+			}
+		}
+		if stmt := strings.TrimSpace(input[start:i]); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		start = i + 1
+	}
+
+	return stmts
+}
+
+func skipSpaces(input string, i int) int {
+	for i < len(input) && (input[i] == ' ' || input[i] == '\t') {
+		i++
+	}
+
+	return i
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+
+	return s
+}
+
+// HasMermaid reports whether the tree rooted at node contains mermaid
+// diagrams which are not rendered to SVG, only pages with them need to
+// load mermaid.js.
+func HasMermaid(node Node) bool {
+	found := false
+	Walk(node, func(n Node) bool {
+		if d, ok := n.(*Diagram); ok && d.Lang == "mermaid" {
+			_, err := diagramSVG(d.Lang, d.Source)
+			found = err != nil
+		}
+		return !found
+	})
+
+	return found
+}
+
+// renderDiagram writes the SVG of a supported diagram, an unsupported
+// mermaid diagram is left to mermaid.js and a dot graph is shown as code.
+func (r *htmlRenderer) renderDiagram(n *Diagram) {
+	if svg, err := diagramSVG(n.Lang, n.Source); err == nil {
+		r.buffer.WriteString(fmt.Sprintf("\n<div class=\"diagram %s\">\n", n.Lang))
+		r.buffer.Write(svg)
+		r.buffer.WriteString("\n</div>\n")
+		return
+	}
+
+	if n.Lang == "mermaid" {
+		r.buffer.WriteString("\n<div class=\"mermaid\">\n")
+		r.writeEscaped(n.Source)
+		r.buffer.WriteString("\n</div>\n")
+		return
+	}
+
+	r.render(&CodeBlock{Position: n.Position, Fenced: true, Info: n.Lang, Lang: n.Lang, Code: n.Source})
+}
@@ -0,0 +1,85 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiagram(t *testing.T) {
+	parser := NewParser()
+	parser.Enable(Diagrams)
+
+	input := [][]byte{
+		[]byte("```mermaid\ngraph TD\n  A[Start] --> B(End)\n```"),
+		[]byte("```mermaid\npie title Pets\n  \"Dogs\" : 386\n```"),
+		[]byte("```dot\ndigraph { subgraph x { a } }\n```"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<div class=\"diagram mermaid\">\n" +
+			"<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"84\" height=\"142\" viewBox=\"0 0 84 142\" font-family=\"sans-serif\" font-size=\"14\" role=\"img\">\n" +
+			"<line x1=\"42\" y1=\"46\" x2=\"42\" y2=\"96\" stroke=\"#333\"/>\n" +
+			"<polygon points=\"42,96 38,86 46,86\" fill=\"#333\"/>\n" +
+			"<rect x=\"10\" y=\"10\" width=\"64\" height=\"36\" fill=\"#fff\" stroke=\"#333\"/>\n" +
+			"<text x=\"42\" y=\"28\" text-anchor=\"middle\" dominant-baseline=\"central\">Start</text>\n" +
+			"<rect x=\"12\" y=\"96\" width=\"60\" height=\"36\" rx=\"18\" fill=\"#fff\" stroke=\"#333\"/>\n" +
+			"<text x=\"42\" y=\"114\" text-anchor=\"middle\" dominant-baseline=\"central\">End</text>\n" +
+			"</svg>\n</div>\n"),
+		[]byte("\n<div class=\"mermaid\">\npie title Pets\n  &quot;Dogs&quot; : 386\n</div>\n"),
+		[]byte("\n<pre lang=\"dot\">\n<code>\ndigraph { subgraph x { a } }\n</code>\n</pre>\n"),
+	}
+	mermaid := []bool{false, true, false}
+
+	for i, v := range input {
+		doc := parser.Parse(v)
+		result := RenderHTML(doc)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("Diagram fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+		if HasMermaid(doc) != mermaid[i] {
+			t.Fatalf("Diagram mermaid fail, [%s] vs [%v]", string(v), mermaid[i])
+		}
+	}
+
+	if _, ok := Parse(input[0]).Blocks[0].(*CodeBlock); !ok {
+		t.Fatalf("Diagram disabled fail, [%s]", string(input[0]))
+	}
+}
+
+func TestDiagramSVG(t *testing.T) {
+	input := []struct {
+		lang   string
+		source string
+	}{
+		{"mermaid", "flowchart LR\n  %% a comment\n  A{Ok?} -->|yes| B; A -.-> C[中文]\n  C --> A"},
+		{"mermaid", "sequenceDiagram\n  participant A as Alice\n  A->>B: Hello <b>\n  B-->>A: Fine\n  B->>B: Think\n  Note over A,B: done"},
+		{"dot", "digraph G {\n  rankdir=LR // left to right\n  a [label=\"Client\", shape=box]\n  a -> b -> \"c d\" [label=req]\n}"},
+		{"dot", "graph { a -- b }"},
+	}
+
+	output := [][]string{
+		{`<polygon points="`, `>Ok?</text>`, `>yes</text>`, `stroke-dasharray="4 3"`, `>中文</text>`, `<path d="M`},
+		{`>Alice</text>`, `>B</text>`, `>Hello &lt;b&gt;</text>`, `fill="#fff8c4"`, `<path d="M`},
+		{`>Client</text>`, `rx="18"`, `>c d</text>`, `>req</text>`},
+		{`>a</text>`, `>b</text>`, `<line x1="40" y1="46" x2="40" y2="96" stroke="#333"/>` + "\n<rect"},
+	}
+
+	for i, v := range input {
+		result, err := diagramSVG(v.lang, []byte(v.source))
+		if err != nil {
+			t.Fatalf("DiagramSVG fail, [%s] vs [%v]", v.source, err)
+		}
+		for _, expect := range output[i] {
+			if !strings.Contains(string(result), expect) {
+				t.Fatalf("DiagramSVG fail, [%s] vs [%s]", string(result), expect)
+			}
+		}
+	}
+
+	for _, v := range []string{"graph RL\n  A --> B", "graph TD\n  A -- text --> B", "sequenceDiagram\n  loop Every minute\n  end"} {
+		if _, err := diagramSVG("mermaid", []byte(v)); err != errDiagram {
+			t.Fatalf("DiagramSVG unsupported fail, [%s] vs [%v]", v, err)
+		}
+	}
+}
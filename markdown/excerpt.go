@@ -0,0 +1,279 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+var reMore *regexp.Regexp
+
+func init() {
+	reMore = regexp.MustCompile(`(?i)^<!--\s*more\s*-->$`)
+}
+
+// Excerpt is the beginning of doc up to a `<!--more-->` marker, or else its
+// text cut to at most limit runes at the end of a sentence or a word, which
+// ends with an ellipsis. Raw HTML blocks and footnote references are left
+// out, so the excerpt renders to balanced markup. The bool reports whether
+// doc is longer than the excerpt.
+func Excerpt(doc *Document, limit int) (*Document, bool) {
+	if blocks, ok := moreBlocks(doc.Blocks); ok {
+		e := &excerpter{budget: -1}
+		return &Document{Position: doc.Position, Blocks: e.blocks(blocks)}, true
+	}
+
+	e := &excerpter{budget: limit}
+	return &Document{Position: doc.Position, Blocks: e.blocks(doc.Blocks)}, e.cut
+}
+
+// moreBlocks is the blocks before the more marker, which is either an HTML
+// block or raw HTML in a paragraph.
+func moreBlocks(blocks []Node) ([]Node, bool) {
+	for i, block := range blocks {
+		switch n := block.(type) {
+		case *HTMLBlock:
+			if reMore.Match(bytes.TrimSpace(n.HTML)) {
+				return blocks[:i], true
+			}
+
+		case *Paragraph:
+			for j, inline := range n.Inlines {
+				if raw, ok := inline.(*RawHTML); ok && reMore.Match(raw.HTML) {
+					p := *n
+					p.Inlines = trimBreaks(n.Inlines[:j])
+					return append(blocks[:i:i], &p), true
+				}
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// excerpter copies blocks until budget runes of text are used, a negative
+// budget copies everything.
+type excerpter struct {
+	budget int
+	cut    bool
+}
+
+func (e *excerpter) blocks(blocks []Node) []Node {
+	var result []Node
+	for _, block := range blocks {
+		if e.cut {
+			break
+		}
+		if node := e.block(block); node != nil {
+			result = append(result, node)
+		}
+	}
+
+	return result
+}
+
+func (e *excerpter) block(node Node) Node {
+	switch n := node.(type) {
+	case *Paragraph:
+		inlines := e.inlines(n.Inlines)
+		if len(inlines) == 0 {
+			return nil
+		}
+		if e.cut && !endsSentence(plainText(inlines)) {
+			inlines = append(inlines, &Text{Text: []byte("…")})
+		}
+		p := *n
+		p.Inlines = inlines
+		return &p
+
+	case *Quote:
+		blocks := e.blocks(n.Blocks)
+		if len(blocks) == 0 {
+			return nil
+		}
+		q := *n
+		q.Blocks = blocks
+		return &q
+
+	case *Admonition:
+		blocks := e.blocks(n.Blocks)
+		if len(blocks) == 0 {
+			return nil
+		}
+		a := *n
+		a.Blocks = blocks
+		return &a
+
+	case *List:
+		var items []*ListItem
+		for _, item := range n.Items {
+			if blocks := e.blocks(item.Blocks); len(blocks) > 0 {
+				it := *item
+				it.Blocks = blocks
+				items = append(items, &it)
+			}
+			if e.cut {
+				break
+			}
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		l := *n
+		l.Items = items
+		return &l
+
+	case *Diagram, *HTMLBlock, *LinkDefinition, *FootnoteDefinition, *AbbreviationDefinition:
+		return nil
+	}
+
+	if !e.take(utf8.RuneCountInString(blockText(node))) {
+		return nil
+	}
+
+	return node
+}
+
+func (e *excerpter) inlines(nodes []Node) []Node {
+	var result []Node
+	for _, node := range nodes {
+		if e.cut {
+			break
+		}
+
+		switch n := node.(type) {
+		case *FootnoteRef:
+			continue
+
+		case *Emphasis:
+			if inlines := e.inlines(n.Inlines); len(inlines) > 0 {
+				em := *n
+				em.Inlines = inlines
+				result = append(result, &em)
+			}
+			continue
+
+		case *Strikethrough:
+			if inlines := e.inlines(n.Inlines); len(inlines) > 0 {
+				del := *n
+				del.Inlines = inlines
+				result = append(result, &del)
+			}
+			continue
+
+		case *Link:
+			if inlines := e.inlines(n.Inlines); len(inlines) > 0 {
+				link := *n
+				link.Inlines = inlines
+				result = append(result, &link)
+			}
+			continue
+
+		case *Text:
+			budget := e.budget
+			if !e.take(utf8.RuneCount(n.Text)) {
+				if text := cutText(n.Text, budget); len(text) > 0 {
+					result = append(result, &Text{Position: n.Position, Text: text})
+				}
+				continue
+			}
+			result = append(result, n)
+			continue
+		}
+
+		if e.take(utf8.RuneCountInString(plainText([]Node{node}))) {
+			result = append(result, node)
+		}
+	}
+
+	return trimBreaks(result)
+}
+
+// take uses size runes of the budget, it reports false and marks the
+// excerpt as cut if they do not fit.
+func (e *excerpter) take(size int) bool {
+	if e.budget < 0 {
+		return true
+	}
+	if size > e.budget {
+		e.cut = true
+		return false
+	}
+	e.budget -= size
+
+	return true
+}
+
+func trimBreaks(nodes []Node) []Node {
+	for len(nodes) > 0 {
+		switch nodes[len(nodes)-1].(type) {
+		case *SoftBreak, *HardBreak:
+			nodes = nodes[:len(nodes)-1]
+		default:
+			return nodes
+		}
+	}
+
+	return nodes
+}
+
+// cutText cuts text to at most n runes, at the end of the last sentence if
+// it is in the second half, or else between two words. Han text may be cut
+// between any two characters, a word which does not fit is dropped.
+func cutText(text []byte, n int) []byte {
+	runes := []rune(string(text))
+	if len(runes) <= n {
+		return text
+	}
+
+	for i := n - 1; i >= n/2 && i > 0; i-- {
+		if isSentenceEnd(runes, i) {
+			return []byte(string(runes[:i+1]))
+		}
+	}
+
+	end := n
+	for end > 0 && isWordRune(runes[end-1]) && isWordRune(runes[end]) {
+		end--
+	}
+	if end == 0 {
+		return nil
+	}
+
+	return bytes.TrimRight([]byte(string(runes[:end])), " \t\n,;:，、；：")
+}
+
+// isSentenceEnd reports whether runes[i] ends a sentence, a full stop must
+// be followed by a space so numbers and abbreviations are not split.
+func isSentenceEnd(runes []rune, i int) bool {
+	switch runes[i] {
+	case '。', '！', '？', '…':
+		return true
+
+	case '.', '!', '?':
+		return i+1 < len(runes) && unicode.IsSpace(runes[i+1])
+	}
+
+	return false
+}
+
+func endsSentence(text string) bool {
+	r, _ := utf8.DecodeLastRuneInString(text)
+	switch r {
+	case '。', '！', '？', '…', '.', '!', '?':
+		return true
+	}
+
+	return false
+}
+
+// isWordRune reports whether r belongs to a word of a language written with
+// spaces between words.
+func isWordRune(r rune) bool {
+	if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) {
+		return false
+	}
+
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' || r == '-'
+}
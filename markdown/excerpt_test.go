@@ -0,0 +1,47 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExcerpt(t *testing.T) {
+	input := [][]byte{
+		[]byte("First paragraph *with emphasis* here.\n\n<!--more-->\n\nSecond."),
+		[]byte("Intro text[^1]\n<!-- more -->\nrest\n\n[^1]: note"),
+		[]byte("这是第一句话。这是第二句话，它比较长一些。这是第三句话。"),
+		[]byte("Hello **wonderful world** of markdown parsers"),
+		[]byte("- one\n- **two three four**\n- five six"),
+		[]byte("# Title\n\nshort"),
+		[]byte("<div>\nraw\n</div>\n\nText after a block. Of html."),
+	}
+
+	output := [][]byte{
+		[]byte("\n<p>First paragraph <em>with emphasis</em> here.</p>\n"),
+		[]byte("\n<p>Intro text</p>\n"),
+		[]byte("\n<p>这是第一句话。这是第二句话，它比较长一些…</p>\n"),
+		[]byte("\n<p>Hello <strong>wonderful</strong>…</p>\n"),
+		[]byte("\n<ul>\n<li>one</li>\n<li><strong>two three four</strong></li>\n</ul>\n"),
+		[]byte("\n<h1 id=\"title\"> Title </h1>\n\n<p>short</p>\n"),
+		[]byte("\n<p>Text after a block.</p>\n"),
+	}
+	cut := []bool{true, true, true, true, true, false, true}
+
+	for i, v := range input {
+		doc, ok := Excerpt(Parse(v), 20)
+		result := RenderHTML(doc)
+		if !bytes.Equal(result, output[i]) || ok != cut[i] {
+			t.Fatalf("Excerpt fail, [%s] %v vs [%s] %v", string(result), ok, string(output[i]), cut[i])
+		}
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	input := []byte("# T\n\n> [!NOTE]\n> a *b* [c](/d)\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n```\ncode\n```\n\n<div>x</div>\n\n1. x\n2. y")
+	output := "T\n\nNote\n\na b c\n\na\tb\n1\t2\n\ncode\n\nx\ny"
+
+	result := string(RenderText(Parse(input)))
+	if result != output {
+		t.Fatalf("RenderText fail, [%s] vs [%s]", result, output)
+	}
+}
@@ -0,0 +1,283 @@
+package markdown
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+)
+
+// Parser is a configurable markdown parser, block and inline parsers
+// registered on it extend the syntax of the package. The zero value parses
+// the same syntax as Parse.
+type Parser struct {
+	blocks     []BlockParser
+	inlines    map[byte][]InlineParser
+	triggers   [256]bool
+	extensions Extension
+}
+
+// Extension is a set of optional syntax which a Parser may enable.
+type Extension int
+
+const (
+	// DefinitionLists parses `Term` lines followed by `: definition`
+	// lines as a <dl>.
+	DefinitionLists Extension = 1 << iota
+	// Abbreviations parses `*[HTML]: title` definitions and wraps the
+	// defined words of the text into <abbr>.
+	Abbreviations
+	// HeadingIDs takes a trailing `{#id}` of a heading as its id instead
+	// of the slug of its text.
+	HeadingIDs
+	// Diagrams renders ```mermaid and ```dot code blocks as diagrams.
+	Diagrams
+)
+
+var defaultParser = &Parser{}
+
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// BlockParser parses a custom block. Open is called on every line which may
+// start a block, a line it accepts also ends the paragraph before it.
+type BlockParser interface {
+	Open(line []byte) bool
+	Parse(c *BlockContext) Node
+}
+
+// InlineParser parses a custom inline element, it is tried at the bytes
+// returned by Triggers before the syntax of the package. Parse returns the
+// node and the number of bytes it consumed, or 0 if nothing matched.
+type InlineParser interface {
+	Triggers() []byte
+	Parse(c *InlineContext) (Node, int)
+}
+
+// Enable turns on the extensions of ext, which may be combined with |.
+func (p *Parser) Enable(ext Extension) {
+	p.extensions |= ext
+}
+
+// AddBlock registers bp, the block parsers are tried in the order they are
+// added and before the syntax of the package.
+func (p *Parser) AddBlock(bp BlockParser) {
+	p.blocks = append(p.blocks, bp)
+}
+
+// AddInline registers ip on every byte returned by its Triggers.
+func (p *Parser) AddInline(ip InlineParser) {
+	if p.inlines == nil {
+		p.inlines = make(map[byte][]InlineParser)
+	}
+	for _, c := range ip.Triggers() {
+		p.inlines[c] = append(p.inlines[c], ip)
+		p.triggers[c] = true
+	}
+}
+
+// opens reports whether a registered block parser accepts the line at
+// index i.
+func (p *blockParser) opens(i int) bool {
+	if p.ext == nil {
+		return false
+	}
+	for _, bp := range p.ext.blocks {
+		if bp.Open(p.lines[i].text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseCustom runs the registered block parsers on the current line, a
+// parser which consumes no line leaves it to the syntax of the package.
+func (p *blockParser) parseCustom() Node {
+	text := p.peek().text
+	for _, bp := range p.ext.blocks {
+		if !bp.Open(text) {
+			continue
+		}
+
+		start := p.pos
+		c := &BlockContext{p: p}
+		node := bp.Parse(c)
+		if p.pos > start && node != nil {
+			return node
+		}
+		p.pos = start
+	}
+
+	return nil
+}
+
+// BlockContext gives a BlockParser access to the lines of its container.
+type BlockContext struct {
+	p     *blockParser
+	taken []line
+}
+
+// Line is the current line, or nil after the last line.
+func (c *BlockContext) Line() []byte {
+	if c.p.pos >= len(c.p.lines) {
+		return nil
+	}
+
+	return c.p.peek().text
+}
+
+// Position is the location of the first non-space byte of the current
+// line.
+func (c *BlockContext) Position() Position {
+	l := c.p.lines[c.p.pos]
+	return Position{Line: l.num, Column: l.col + len(leadingSpace(l.text))}
+}
+
+// Done reports whether all lines of the container are consumed.
+func (c *BlockContext) Done() bool {
+	return c.p.pos >= len(c.p.lines)
+}
+
+// Advance consumes the current line.
+func (c *BlockContext) Advance() {
+	c.p.pos++
+}
+
+// Take consumes the current line and keeps it without its first skip bytes
+// as content of the block, which ParseChildren parses.
+func (c *BlockContext) Take(skip int) {
+	l := c.p.peek()
+	if skip > len(l.text) {
+		skip = len(l.text)
+	}
+	c.taken = append(c.taken, line{text: l.text[skip:], num: l.num, col: l.col + skip})
+	c.p.pos++
+}
+
+// Interrupts reports whether the current line starts a block which ends a
+// paragraph, a block parser may stop there.
+func (c *BlockContext) Interrupts() bool {
+	return !c.Done() && c.p.interrupts(c.p.pos)
+}
+
+// ParseChildren parses the lines kept by Take into blocks, with the same
+// parser as the document.
+func (c *BlockContext) ParseChildren() []Node {
+	blocks := c.p.ext.parseBlocks(c.taken)
+	c.taken = nil
+	return blocks
+}
+
+// parseCustom runs the inline parsers registered on input[i].
+func (p *inlineParser) parseCustom(i int) int {
+	if p.ext == nil {
+		return i
+	}
+
+	for _, ip := range p.ext.inlines[p.input[i]] {
+		node, n := ip.Parse(&InlineContext{p: p, offset: i})
+		if n > 0 && node != nil {
+			p.flushText()
+			p.append(node)
+			return i + n
+		}
+	}
+
+	return i
+}
+
+// InlineContext gives an InlineParser access to the text of the leaf
+// block.
+type InlineContext struct {
+	p      *inlineParser
+	offset int
+}
+
+// Input is the text from the trigger byte to the end of the leaf block.
+func (c *InlineContext) Input() []byte {
+	return c.p.input[c.offset:]
+}
+
+// Position is the location of the trigger byte.
+func (c *InlineContext) Position() Position {
+	return c.p.position(c.offset)
+}
+
+// ParseInlines parses input[start:end] of Input into inline nodes, with
+// the same parser as the document.
+func (c *InlineContext) ParseInlines(start, end int) []Node {
+	p := &inlineParser{ext: c.p.ext, links: c.p.links, footnotes: c.p.footnotes}
+	return p.parse(c.Input()[start:end], c.p.position(c.offset+start))
+}
+
+// NodeRenderer writes node to w as HTML.
+type NodeRenderer func(w *HTMLWriter, node Node)
+
+// Renderer is a configurable HTML renderer, functions registered on it
+// render custom nodes or replace the rendering of the nodes of the package.
+type Renderer struct {
+	opts  Options
+	funcs map[reflect.Type]NodeRenderer
+}
+
+func NewRenderer(opts Options) *Renderer {
+	return &Renderer{opts: opts}
+}
+
+// Register renders the nodes of the same type as node with fn.
+func (r *Renderer) Register(node Node, fn NodeRenderer) {
+	if r.funcs == nil {
+		r.funcs = make(map[reflect.Type]NodeRenderer)
+	}
+	r.funcs[reflect.TypeOf(node)] = fn
+}
+
+// Render walks the tree rooted at node and renders it to HTML.
+func (r *Renderer) Render(node Node) []byte {
+	hr := &htmlRenderer{buffer: new(bytes.Buffer), opts: r.opts, funcs: r.funcs}
+	hr.render(node)
+	return hr.buffer.Bytes()
+}
+
+// RenderTo renders the tree rooted at node to w, a document is written
+// block by block through a reused buffer.
+func (r *Renderer) RenderTo(w io.Writer, node Node) error {
+	buffer := bufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	defer bufferPool.Put(buffer)
+
+	hr := &htmlRenderer{buffer: buffer, opts: r.opts, funcs: r.funcs, out: w}
+	hr.render(node)
+	hr.flush()
+
+	return hr.err
+}
+
+// HTMLWriter is the output of a NodeRenderer.
+type HTMLWriter struct {
+	r *htmlRenderer
+}
+
+func (w *HTMLWriter) Write(b []byte) (int, error) {
+	return w.r.buffer.Write(b)
+}
+
+func (w *HTMLWriter) WriteString(s string) (int, error) {
+	return w.r.buffer.WriteString(s)
+}
+
+// WriteEscaped writes text with the special characters of HTML escaped.
+func (w *HTMLWriter) WriteEscaped(text []byte) {
+	w.r.writeEscaped(text)
+}
+
+// Render renders nodes with the same renderer, it is used for children.
+func (w *HTMLWriter) Render(nodes ...Node) {
+	w.r.renderBlocks(nodes)
+}
+
+// Options is the options of the renderer.
+func (w *HTMLWriter) Options() Options {
+	return w.r.opts
+}
@@ -0,0 +1,123 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+)
+
+type boxBlock struct {
+	Position
+	Blocks []Node
+}
+
+func (b *boxBlock) Children() []Node {
+	return b.Blocks
+}
+
+// boxParser parses `:::` fenced containers.
+type boxParser struct{}
+
+func (boxParser) Open(line []byte) bool {
+	return bytes.HasPrefix(line, []byte(":::"))
+}
+
+func (boxParser) Parse(c *BlockContext) Node {
+	box := &boxBlock{Position: c.Position()}
+	c.Advance()
+	for !c.Done() && !bytes.Equal(c.Line(), []byte(":::")) {
+		c.Take(0)
+	}
+	if c.Done() {
+		return nil
+	}
+	c.Advance()
+	box.Blocks = c.ParseChildren()
+
+	return box
+}
+
+type mention struct {
+	Position
+	Name []byte
+}
+
+func (m *mention) Children() []Node {
+	return nil
+}
+
+type mentionParser struct{}
+
+func (mentionParser) Triggers() []byte {
+	return []byte("@")
+}
+
+func (mentionParser) Parse(c *InlineContext) (Node, int) {
+	input := c.Input()
+	n := 1
+	for n < len(input) && isAlnum(input[n]) {
+		n++
+	}
+	if n == 1 {
+		return nil, 0
+	}
+
+	return &mention{Position: c.Position(), Name: input[1:n]}, n
+}
+
+func TestExtension(t *testing.T) {
+	p := NewParser()
+	p.AddBlock(boxParser{})
+	p.AddInline(mentionParser{})
+
+	r := NewRenderer(Options{})
+	r.Register(&boxBlock{}, func(w *HTMLWriter, node Node) {
+		w.WriteString("\n<div class=\"box\">")
+		w.Render(node.(*boxBlock).Blocks...)
+		w.WriteString("</div>\n")
+	})
+	r.Register(&mention{}, func(w *HTMLWriter, node Node) {
+		w.WriteString("<a href=\"/u/")
+		w.WriteEscaped(node.(*mention).Name)
+		w.WriteString("\">@")
+		w.WriteEscaped(node.(*mention).Name)
+		w.WriteString("</a>")
+	})
+	r.Register(&CodeSpan{}, func(w *HTMLWriter, node Node) {
+		w.WriteString("<kbd>")
+		w.WriteEscaped(node.(*CodeSpan).Code)
+		w.WriteString("</kbd>")
+	})
+
+	input := [][]byte{
+		[]byte(":::\nhi @bob, *press* `q`\n:::"),
+		[]byte("text\n:::\n# Title\n:::"),
+		[]byte(":::\nnot closed"),
+		[]byte("mail a@ or @@"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<div class=\"box\">\n<p>hi <a href=\"/u/bob\">@bob</a>, <em>press</em> <kbd>q</kbd></p>\n</div>\n"),
+		[]byte("\n<p>text</p>\n\n<div class=\"box\">\n<h1 id=\"title\"> Title </h1>\n</div>\n"),
+		[]byte("\n<p>:::\nnot closed</p>\n"),
+		[]byte("\n<p>mail a@ or @@</p>\n"),
+	}
+
+	for i, v := range input {
+		result := r.Render(p.Parse(v))
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("Extension fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+
+	// the package level functions are not affected by the registrations
+	result := Render([]byte("hi @bob `q`"))
+	if string(result) != "\n<p>hi @bob <code>q</code></p>\n" {
+		t.Fatalf("Extension fail, [%s]", string(result))
+	}
+
+	doc := p.Parse([]byte("line\n\n  x @ann"))
+	m := doc.Blocks[1].(*Paragraph).Inlines[1].(*mention)
+	if m.Line != 3 || m.Column != 5 {
+		t.Fatalf("Extension fail, position %d:%d", m.Line, m.Column)
+	}
+}
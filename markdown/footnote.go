@@ -0,0 +1,220 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	reFootnoteDef *regexp.Regexp
+	reFootnoteRef *regexp.Regexp
+)
+
+func init() {
+	reFootnoteDef = regexp.MustCompile(`^ {0,3}\[\^([^\]\s]+)\]:[ \t]*`)
+	reFootnoteRef = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+}
+
+// footnote is the rendering state of one referenced footnote.
+type footnote struct {
+	def   *FootnoteDefinition
+	index int
+	refs  int
+}
+
+// parseFootnoteDefinition consumes a `[^label]: text` definition, the
+// lines indented by four spaces below it belong to the footnote.
+func (p *blockParser) parseFootnoteDefinition() Node {
+	l := p.peek()
+	p.pos++
+
+	ret := reFootnoteDef.FindSubmatchIndex(l.text)
+	def := &FootnoteDefinition{
+		Position: Position{Line: l.num, Column: l.col + ret[2] - 2},
+		Label:    footnoteLabel(l.text[ret[2]:ret[3]]),
+	}
+
+	lines := []line{{text: l.text[ret[1]:], num: l.num, col: l.col + ret[1]}}
+	for p.pos < len(p.lines) {
+		l := p.peek()
+		if isBlank(l.text) {
+			next := p.pos
+			for next < len(p.lines) && isBlank(p.lines[next].text) {
+				next++
+			}
+			if next == len(p.lines) || indentWidth(leadingSpace(p.lines[next].text)) < 4 {
+				break
+			}
+			for ; p.pos < next; p.pos++ {
+				lines = append(lines, line{num: p.peek().num, col: 1})
+			}
+			continue
+		}
+
+		if indentWidth(leadingSpace(l.text)) >= 4 {
+			lines = append(lines, line{text: stripIndent(l.text, 4), num: l.num, col: l.col + 4})
+			p.pos++
+			continue
+		}
+
+		if isBlank(lines[len(lines)-1].text) || p.interrupts(p.pos) {
+			break
+		}
+		l.lazy = true
+		lines = append(lines, l)
+		p.pos++
+	}
+	def.Blocks = p.ext.parseBlocks(lines)
+
+	return def
+}
+
+func footnoteLabel(label []byte) string {
+	return strings.ToLower(string(label))
+}
+
+// collectFootnotes maps the labels of doc to their definitions, the first
+// definition of a label wins.
+func collectFootnotes(doc *Document) map[string]*FootnoteDefinition {
+	defs := make(map[string]*FootnoteDefinition)
+	Walk(doc, func(node Node) bool {
+		if def, ok := node.(*FootnoteDefinition); ok {
+			if _, exist := defs[def.Label]; !exist {
+				defs[def.Label] = def
+			}
+		}
+		return true
+	})
+
+	return defs
+}
+
+// footnoteRef renders the reference to a footnote, footnotes are numbered
+// in the order of their first reference.
+func (r *htmlRenderer) footnoteRef(label string) []byte {
+	fn, ok := r.footnotes[label]
+	if !ok {
+		def, exist := r.footnoteDefs[label]
+		if !exist {
+			return escapeHTML([]byte("[^" + label + "]"))
+		}
+		fn = &footnote{def: def, index: len(r.footnoteOrder) + 1}
+		r.footnotes[label] = fn
+		r.footnoteOrder = append(r.footnoteOrder, fn)
+	}
+
+	fn.refs++
+	id := fmt.Sprintf("fnref-%d", fn.index)
+	if fn.refs > 1 {
+		id = fmt.Sprintf("fnref-%d-%d", fn.index, fn.refs)
+	}
+
+	return []byte(fmt.Sprintf("<sup class=\"footnote-ref\" id=\"%s\"><a href=\"#fn-%d\">%d</a></sup>", id, fn.index, fn.index))
+}
+
+// renderFootnotes writes the footnotes section, every footnote ends with a
+// back-link to each of its references.
+func (r *htmlRenderer) renderFootnotes() {
+	if len(r.footnoteOrder) == 0 {
+		return
+	}
+
+	r.buffer.WriteString("\n<section class=\"footnotes\">\n<ol>\n")
+	// footnotes may reference footnotes, which are appended to the order
+	for i := 0; i < len(r.footnoteOrder); i++ {
+		fn := r.footnoteOrder[i]
+
+		var backrefs bytes.Buffer
+		for j := 1; j <= fn.refs; j++ {
+			id := fmt.Sprintf("fnref-%d", fn.index)
+			if j > 1 {
+				id = fmt.Sprintf("fnref-%d-%d", fn.index, j)
+			}
+			backrefs.WriteString(fmt.Sprintf(" <a href=\"#%s\" class=\"footnote-backref\">&#8617;</a>", id))
+		}
+
+		r.buffer.WriteString(fmt.Sprintf("<li id=\"fn-%d\">", fn.index))
+		blocks := fn.def.Blocks
+		var last *Paragraph
+		if len(blocks) > 0 {
+			last, _ = blocks[len(blocks)-1].(*Paragraph)
+		}
+		if last != nil {
+			r.renderBlocks(blocks[:len(blocks)-1])
+			r.buffer.WriteString("\n<p>")
+			r.renderBlocks(last.Inlines)
+			r.buffer.Write(backrefs.Bytes())
+			r.buffer.WriteString("</p>\n")
+		} else {
+			r.renderBlocks(blocks)
+			r.buffer.Write(backrefs.Bytes())
+		}
+		r.buffer.WriteString("</li>\n")
+	}
+	r.buffer.WriteString("</ol>\n</section>\n")
+}
+
+// checkFootnotes warns about references to undefined footnotes, and about
+// footnotes which are defined twice or never referenced.
+func checkFootnotes(doc *Document) []Warning {
+	var warnings []Warning
+
+	defs := make(map[string]*FootnoteDefinition)
+	referenced := make(map[string]bool)
+	Walk(doc, func(node Node) bool {
+		if def, ok := node.(*FootnoteDefinition); ok {
+			if _, exist := defs[def.Label]; exist {
+				warnings = append(warnings, Warning{
+					Position: def.Position,
+					Message:  fmt.Sprintf("footnote [^%s] is defined more than once", def.Label),
+				})
+			} else {
+				defs[def.Label] = def
+			}
+		}
+		return true
+	})
+
+	// references to undefined footnotes are left as text
+	Walk(doc, func(node Node) bool {
+		switch n := node.(type) {
+		case *FootnoteRef:
+			referenced[n.Label] = true
+
+		case *Text:
+			for _, ret := range reFootnoteRef.FindAllSubmatchIndex(n.Text, -1) {
+				warnings = append(warnings, Warning{
+					Position: Position{Line: n.Line, Column: n.Column + ret[0]},
+					Message:  fmt.Sprintf("footnote [^%s] is not defined", footnoteLabel(n.Text[ret[2]:ret[3]])),
+				})
+			}
+		}
+		return true
+	})
+
+	Walk(doc, func(node Node) bool {
+		if def, ok := node.(*FootnoteDefinition); ok && defs[def.Label] == def && !referenced[def.Label] {
+			warnings = append(warnings, Warning{
+				Position: def.Position,
+				Message:  fmt.Sprintf("footnote [^%s] is never referenced", def.Label),
+			})
+		}
+		return true
+	})
+
+	return warnings
+}
+
+// textPosition is the position of text[offset], for inline text starting
+// at pos.
+func textPosition(pos Position, text []byte, offset int) Position {
+	lines := bytes.Count(text[:offset], lineTrail)
+	if lines == 0 {
+		return Position{Line: pos.Line, Column: pos.Column + offset}
+	}
+
+	start := bytes.LastIndex(text[:offset], lineTrail) + 1
+	return Position{Line: pos.Line + lines, Column: offset - start + 1}
+}
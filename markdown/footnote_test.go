@@ -0,0 +1,53 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFootnote(t *testing.T) {
+	input := [][]byte{
+		[]byte("See the paper[^paper] and the code[^code].\n\n[^code]: On GitHub.\n[^paper]: Attention Is All You Need."),
+		[]byte("Cited twice[^1], and again[^1].\n\n[^1]: A note\n    with more lines.\n\n    And a second paragraph."),
+		[]byte("Missing[^none] stays as it is."),
+	}
+
+	output := [][]byte{
+		[]byte("\n<p>See the paper<sup class=\"footnote-ref\" id=\"fnref-1\"><a href=\"#fn-1\">1</a></sup> and the code<sup class=\"footnote-ref\" id=\"fnref-2\"><a href=\"#fn-2\">2</a></sup>.</p>\n" +
+			"\n<section class=\"footnotes\">\n<ol>\n" +
+			"<li id=\"fn-1\">\n<p>Attention Is All You Need. <a href=\"#fnref-1\" class=\"footnote-backref\">&#8617;</a></p>\n</li>\n" +
+			"<li id=\"fn-2\">\n<p>On GitHub. <a href=\"#fnref-2\" class=\"footnote-backref\">&#8617;</a></p>\n</li>\n" +
+			"</ol>\n</section>\n"),
+		[]byte("\n<p>Cited twice<sup class=\"footnote-ref\" id=\"fnref-1\"><a href=\"#fn-1\">1</a></sup>, and again<sup class=\"footnote-ref\" id=\"fnref-1-2\"><a href=\"#fn-1\">1</a></sup>.</p>\n" +
+			"\n<section class=\"footnotes\">\n<ol>\n" +
+			"<li id=\"fn-1\">\n<p>A note\nwith more lines.</p>\n\n<p>And a second paragraph. <a href=\"#fnref-1\" class=\"footnote-backref\">&#8617;</a> <a href=\"#fnref-1-2\" class=\"footnote-backref\">&#8617;</a></p>\n</li>\n" +
+			"</ol>\n</section>\n"),
+		[]byte("\n<p>Missing[^none] stays as it is.</p>\n"),
+	}
+
+	for i, v := range input {
+		result := Render(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("Footnote fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
+func TestCheckFootnote(t *testing.T) {
+	input := []byte("Text[^a] and\nmore[^b].\n\n[^a]: defined\n[^c]: unused\n[^a]: again")
+	warnings := Check(Parse(input))
+
+	output := []string{
+		"6:1: footnote [^a] is defined more than once",
+		"2:5: footnote [^b] is not defined",
+		"5:1: footnote [^c] is never referenced",
+	}
+	if len(warnings) != len(output) {
+		t.Fatalf("CheckFootnote fail, %v vs %v", warnings, output)
+	}
+	for i, w := range warnings {
+		if w.String() != output[i] {
+			t.Fatalf("CheckFootnote fail, [%s] vs [%s]", w, output[i])
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// HighlightOptions enables syntax highlighting of fenced code blocks, the
+// tokens are wrapped in <span> with the classes styled by style.css.
+type HighlightOptions struct {
+	// LineNumbers prefixes every line with its number.
+	LineNumbers bool
+	// Plain renders languages without a lexer as plain code, otherwise
+	// they are highlighted by a generic lexer.
+	Plain bool
+}
+
+type tokenType int
+
+const (
+	tokenText tokenType = iota
+	tokenKeyword
+	tokenBuiltin
+	tokenString
+	tokenNumber
+	tokenComment
+	tokenVariable
+	tokenFunction
+	tokenPreproc
+)
+
+func (tp tokenType) String() string {
+	switch tp {
+	case tokenKeyword:
+		return "keyword"
+
+	case tokenBuiltin:
+		return "builtin"
+
+	case tokenString:
+		return "string"
+
+	case tokenNumber:
+		return "number"
+
+	case tokenComment:
+		return "comment"
+
+	case tokenVariable:
+		return "variable"
+
+	case tokenFunction:
+		return "function"
+
+	case tokenPreproc:
+		return "preproc"
+
+	default:
+		return ""
+	}
+}
+
+type token struct {
+	tp   tokenType
+	data []byte
+}
+
+// lexer describes the syntax of one language, the same scanner is used for
+// all of them.
+type lexer struct {
+	keywords      map[string]bool
+	builtins      map[string]bool
+	lineComments  []string
+	blockComments [][2]string
+	// strings lists the string delimiters, longest first
+	strings    []string
+	rawStrings map[string]bool
+	// wordComments only starts line comments at the beginning of a word
+	wordComments bool
+	variables    bool
+	preproc      bool
+	functions    bool
+}
+
+var reLineRange *regexp.Regexp
+
+func init() {
+	reLineRange = regexp.MustCompile(`\{([\d\s,-]*)\}`)
+}
+
+// highlightLines parses the `{3,5-7}` part of a fence info string, the
+// ranges are cut to the count lines of the code.
+func highlightLines(info string, count int) map[int]bool {
+	ret := reLineRange.FindStringSubmatch(info)
+	if ret == nil {
+		return nil
+	}
+
+	lines := make(map[int]bool)
+	for _, part := range strings.Split(ret[1], ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		start, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			continue
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+				continue
+			}
+		}
+		for i := maxInt(start, 1); i <= minInt(end, count); i++ {
+			lines[i] = true
+		}
+	}
+
+	return lines
+}
+
+func (r *htmlRenderer) renderHighlight(code *CodeBlock) bool {
+	opts := r.opts.Highlight
+
+	l, ok := lexers[strings.ToLower(code.Lang)]
+	if !ok {
+		if opts.Plain || code.Lang == "" {
+			return false
+		}
+		l = genericLexer
+	}
+
+	lines := splitTokens(l.lex(code.Code))
+	marked := highlightLines(code.Info, len(lines))
+
+	r.buffer.WriteString(fmt.Sprintf("\n<pre lang=\"%s\" class=\"highlight\">\n<code>\n", escapeHTML([]byte(code.Lang))))
+	for i, tokens := range lines {
+		if i > 0 {
+			r.buffer.WriteString("\n")
+		}
+
+		wrap := opts.LineNumbers || len(marked) > 0
+		if wrap {
+			if marked[i+1] {
+				r.buffer.WriteString("<span class=\"line hl\">")
+			} else {
+				r.buffer.WriteString("<span class=\"line\">")
+			}
+		}
+		if opts.LineNumbers {
+			r.buffer.WriteString(fmt.Sprintf("<span class=\"ln\">%d</span>", i+1))
+		}
+
+		for _, t := range tokens {
+			if t.tp == tokenText {
+				r.writeEscaped(t.data)
+				continue
+			}
+			r.buffer.WriteString(fmt.Sprintf("<span class=\"%s\">", t.tp))
+			r.writeEscaped(t.data)
+			r.buffer.WriteString("</span>")
+		}
+
+		if wrap {
+			r.buffer.WriteString("</span>")
+		}
+	}
+	r.buffer.WriteString("\n</code>\n</pre>\n")
+
+	return true
+}
+
+// splitTokens splits tokens at line breaks, so every line can be wrapped
+// on its own.
+func splitTokens(tokens []token) [][]token {
+	lines := [][]token{nil}
+	for _, t := range tokens {
+		parts := bytes.Split(t.data, lineTrail)
+		for i, part := range parts {
+			if i > 0 {
+				lines = append(lines, nil)
+			}
+			if len(part) > 0 {
+				last := len(lines) - 1
+				lines[last] = append(lines[last], token{tp: t.tp, data: part})
+			}
+		}
+	}
+
+	return lines
+}
+
+func (l *lexer) lex(code []byte) []token {
+	var tokens []token
+	var text int
+
+	emit := func(start, end int, tp tokenType) {
+		if text < start {
+			tokens = append(tokens, token{tp: tokenText, data: code[text:start]})
+		}
+		tokens = append(tokens, token{tp: tp, data: code[start:end]})
+		text = end
+	}
+
+	for i := 0; i < len(code); {
+		if end := l.comment(code, i); end > i {
+			emit(i, end, tokenComment)
+			i = end
+			continue
+		}
+
+		if l.preproc && code[i] == '#' && isLineStart(code, i) {
+			end := lineEnd(code, i)
+			emit(i, end, tokenPreproc)
+			i = end
+			continue
+		}
+
+		if end := l.str(code, i); end > i {
+			emit(i, end, tokenString)
+			i = end
+			continue
+		}
+
+		if l.variables && code[i] == '$' {
+			if end := variableEnd(code, i); end > i+1 {
+				emit(i, end, tokenVariable)
+				i = end
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRune(code[i:])
+		switch {
+		case isDigit(code[i]) && (i == 0 || !isWord(code[i-1])):
+			end := i
+			for end < len(code) && (isWord(code[end]) || code[end] == '.') {
+				end++
+			}
+			emit(i, end, tokenNumber)
+			i = end
+
+		case r == '_' || unicode.IsLetter(r):
+			end := i
+			for end < len(code) {
+				r, size := utf8.DecodeRune(code[end:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				end += size
+			}
+
+			word := string(code[i:end])
+			switch {
+			case l.keywords[word]:
+				emit(i, end, tokenKeyword)
+			case l.builtins[word]:
+				emit(i, end, tokenBuiltin)
+			case l.functions && end < len(code) && code[end] == '(':
+				emit(i, end, tokenFunction)
+			}
+			i = end
+
+		default:
+			i += size
+		}
+	}
+
+	if text < len(code) {
+		tokens = append(tokens, token{tp: tokenText, data: code[text:]})
+	}
+
+	return tokens
+}
+
+// comment returns the end of the comment starting at i, or i if there is
+// none.
+func (l *lexer) comment(code []byte, i int) int {
+	for _, c := range l.lineComments {
+		if !bytes.HasPrefix(code[i:], []byte(c)) {
+			continue
+		}
+		if l.wordComments && i > 0 && !isSpace(code[i-1]) {
+			continue
+		}
+		return lineEnd(code, i)
+	}
+
+	for _, c := range l.blockComments {
+		if !bytes.HasPrefix(code[i:], []byte(c[0])) {
+			continue
+		}
+		end := bytes.Index(code[i+len(c[0]):], []byte(c[1]))
+		if end < 0 {
+			return len(code)
+		}
+		return i + len(c[0]) + end + len(c[1])
+	}
+
+	return i
+}
+
+// str returns the end of the string literal starting at i, or i if there
+// is none. A string which is not closed ends at the end of the line.
+func (l *lexer) str(code []byte, i int) int {
+	for _, delim := range l.strings {
+		if !bytes.HasPrefix(code[i:], []byte(delim)) {
+			continue
+		}
+
+		raw := l.rawStrings[delim]
+		multiline := raw || len(delim) == 3
+		for j := i + len(delim); j < len(code); j++ {
+			switch {
+			case code[j] == '\\' && !raw:
+				j++
+			case code[j] == '\n' && !multiline:
+				return j
+			case bytes.HasPrefix(code[j:], []byte(delim)):
+				return j + len(delim)
+			}
+		}
+		return len(code)
+	}
+
+	return i
+}
+
+// variableEnd returns the end of a shell variable such as $HOME, ${HOME}
+// or $?, starting at i.
+func variableEnd(code []byte, i int) int {
+	end := i + 1
+	if end >= len(code) {
+		return i
+	}
+
+	switch {
+	case code[end] == '{':
+		if j := bytes.IndexByte(code[end:], '}'); j > 0 {
+			return end + j + 1
+		}
+		return i
+
+	case strings.IndexByte("?#@$!*-", code[end]) >= 0 || isDigit(code[end]):
+		return end + 1
+	}
+
+	for end < len(code) && isWord(code[end]) {
+		end++
+	}
+
+	return end
+}
+
+func lineEnd(code []byte, i int) int {
+	if end := bytes.IndexByte(code[i:], '\n'); end >= 0 {
+		return i + end
+	}
+
+	return len(code)
+}
+
+func isLineStart(code []byte, i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		switch code[j] {
+		case '\n':
+			return true
+		case ' ', '\t':
+			continue
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWord(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,81 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	input := [][]byte{
+		[]byte("```go\nfunc main() { // start\n\tfmt.Println(\"a < b\", 42)\n}\n```"),
+		[]byte("```python\ndef f(x):\n    \"\"\"doc\n    string\"\"\"\n    return None\n```"),
+		[]byte("```sh\necho $HOME ${PATH} # comment\nurl=a#b\n```"),
+		[]byte("```c\n#include <stdio.h>\nint x = 1;\n```"),
+		[]byte("```unknown\nx = \"s\" # note\n```"),
+		[]byte("```\nno language\n```"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<pre lang=\"go\" class=\"highlight\">\n<code>\n<span class=\"keyword\">func</span> <span class=\"function\">main</span>() { <span class=\"comment\">// start</span>\n\tfmt.<span class=\"function\">Println</span>(<span class=\"string\">&quot;a &lt; b&quot;</span>, <span class=\"number\">42</span>)\n}\n</code>\n</pre>\n"),
+		[]byte("\n<pre lang=\"python\" class=\"highlight\">\n<code>\n<span class=\"keyword\">def</span> <span class=\"function\">f</span>(x):\n    <span class=\"string\">&quot;&quot;&quot;doc</span>\n<span class=\"string\">    string&quot;&quot;&quot;</span>\n    <span class=\"keyword\">return</span> <span class=\"keyword\">None</span>\n</code>\n</pre>\n"),
+		[]byte("\n<pre lang=\"sh\" class=\"highlight\">\n<code>\n<span class=\"builtin\">echo</span> <span class=\"variable\">$HOME</span> <span class=\"variable\">${PATH}</span> <span class=\"comment\"># comment</span>\nurl=a#b\n</code>\n</pre>\n"),
+		[]byte("\n<pre lang=\"c\" class=\"highlight\">\n<code>\n<span class=\"preproc\">#include &lt;stdio.h&gt;</span>\n<span class=\"builtin\">int</span> x = <span class=\"number\">1</span>;\n</code>\n</pre>\n"),
+		[]byte("\n<pre lang=\"unknown\" class=\"highlight\">\n<code>\nx = <span class=\"string\">&quot;s&quot;</span> <span class=\"comment\"># note</span>\n</code>\n</pre>\n"),
+		[]byte("\n<pre>\n<code>\nno language\n</code>\n</pre>\n"),
+	}
+
+	opts := Options{Highlight: &HighlightOptions{}}
+	for i, v := range input {
+		result := RenderWithOptions(v, opts)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("Highlight fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
+func TestHighlightLines(t *testing.T) {
+	input := []byte("```go {2,4-5}\na\nb\nc\nd\ne\n```")
+
+	opts := []Options{
+		{Highlight: &HighlightOptions{}},
+		{Highlight: &HighlightOptions{LineNumbers: true}},
+	}
+
+	output := [][]byte{
+		[]byte("\n<pre lang=\"go\" class=\"highlight\">\n<code>\n<span class=\"line\">a</span>\n<span class=\"line hl\">b</span>\n<span class=\"line\">c</span>\n<span class=\"line hl\">d</span>\n<span class=\"line hl\">e</span>\n</code>\n</pre>\n"),
+		[]byte("\n<pre lang=\"go\" class=\"highlight\">\n<code>\n<span class=\"line\"><span class=\"ln\">1</span>a</span>\n<span class=\"line hl\"><span class=\"ln\">2</span>b</span>\n<span class=\"line\"><span class=\"ln\">3</span>c</span>\n<span class=\"line hl\"><span class=\"ln\">4</span>d</span>\n<span class=\"line hl\"><span class=\"ln\">5</span>e</span>\n</code>\n</pre>\n"),
+	}
+
+	for i, v := range opts {
+		result := RenderWithOptions(input, v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("HighlightLines fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
+func TestHighlightLinesRange(t *testing.T) {
+	input := []string{
+		"```go {1-2000000000}\na\nb\n```",
+		"```go {0-9223372036854775807}\na\nb\n```",
+		"```go {0-1,2-99}\na\nb\n```",
+	}
+	output := "\n<pre lang=\"go\" class=\"highlight\">\n<code>\n<span class=\"line hl\">a</span>\n<span class=\"line hl\">b</span>\n</code>\n</pre>\n"
+
+	for _, v := range input {
+		result := RenderWithOptions([]byte(v), Options{Highlight: &HighlightOptions{}})
+		if string(result) != output {
+			t.Fatalf("HighlightLines range fail, [%s] vs [%s]", result, output)
+		}
+	}
+}
+
+func TestHighlightPlain(t *testing.T) {
+	input := []byte("```unknown\nx = \"s\"\n```")
+	output := []byte("\n<pre lang=\"unknown\">\n<code>\nx = &quot;s&quot;\n</code>\n</pre>\n")
+
+	result := RenderWithOptions(input, Options{Highlight: &HighlightOptions{Plain: true}})
+	if !bytes.Equal(result, output) {
+		t.Fatalf("HighlightPlain fail, [%s] vs [%s]", string(result), string(output))
+	}
+}
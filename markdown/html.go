@@ -0,0 +1,361 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Options controls how a document is rendered to HTML.
+type Options struct {
+	HTML HTMLMode
+	// Policy is the allow-list used by HTMLSanitize, DefaultPolicy is used
+	// if it is nil.
+	Policy *Policy
+	// Highlight enables syntax highlighting of code blocks if it is not
+	// nil.
+	Highlight *HighlightOptions
+	// HeadingAnchors adds a permalink to the id of every heading, shown on
+	// hover by style.css.
+	HeadingAnchors bool
+	Math           MathMode
+	// Typography enables smart quotes, dashes, ellipses and CJK spacing if
+	// it is not nil.
+	Typography *TypographyOptions
+}
+
+type htmlRenderer struct {
+	buffer *bytes.Buffer
+	opts   Options
+	tight  bool
+	typo   typography
+
+	// out receives the buffer after every top level block when the
+	// document is streamed, err is the first error of out
+	out io.Writer
+	err error
+
+	footnoteDefs  map[string]*FootnoteDefinition
+	footnotes     map[string]*footnote
+	footnoteOrder []*footnote
+
+	funcs map[reflect.Type]NodeRenderer
+}
+
+// RenderHTML walks the tree rooted at node and renders it to HTML with
+// the default options.
+func RenderHTML(node Node) []byte {
+	return RenderHTMLWithOptions(node, Options{})
+}
+
+func RenderHTMLWithOptions(node Node, opts Options) []byte {
+	return NewRenderer(opts).Render(node)
+}
+
+func (r *htmlRenderer) render(node Node) {
+	if fn, ok := r.funcs[reflect.TypeOf(node)]; ok {
+		fn(&HTMLWriter{r: r}, node)
+		return
+	}
+
+	switch n := node.(type) {
+	case *Document:
+		r.footnoteDefs = collectFootnotes(n)
+		r.footnotes = make(map[string]*footnote)
+		for _, block := range n.Blocks {
+			r.render(block)
+			r.flush()
+		}
+		r.renderFootnotes()
+		r.flush()
+
+	case *Heading:
+		r.resetTypography()
+		if n.ID != "" {
+			r.buffer.WriteString(fmt.Sprintf("\n<h%d id=\"%s\"> ", n.Level, escapeHTML([]byte(n.ID))))
+		} else {
+			r.buffer.WriteString(fmt.Sprintf("\n<h%d> ", n.Level))
+		}
+		r.renderBlocks(n.Inlines)
+		if r.opts.HeadingAnchors && n.ID != "" {
+			r.buffer.WriteString(fmt.Sprintf(" <a class=\"anchor\" href=\"#%s\">#</a>", escapeHTML([]byte(n.ID))))
+		}
+		r.buffer.WriteString(fmt.Sprintf(" </h%d>\n", n.Level))
+
+	case *Paragraph:
+		r.resetTypography()
+		if r.tight {
+			r.renderBlocks(n.Inlines)
+			return
+		}
+		r.buffer.WriteString("\n<p>")
+		r.renderBlocks(n.Inlines)
+		r.buffer.WriteString("</p>\n")
+
+	case *Image:
+		if !SafeURL(string(n.Src), true) {
+			r.buffer.WriteString("\n<p>")
+			r.writeEscaped(n.Alt)
+			r.buffer.WriteString("</p>\n")
+			return
+		}
+		src := escapeHTML([]byte(normalizeURL(string(n.Src))))
+		if len(n.Title) > 0 {
+			r.buffer.WriteString(fmt.Sprintf("\n<img src=\"%s\" alt=\"%s\" title=\"%s\">\n", src, escapeHTML(n.Alt), escapeHTML(n.Title)))
+			return
+		}
+		r.buffer.WriteString(fmt.Sprintf("\n<img src=\"%s\" alt=\"%s\">\n", src, escapeHTML(n.Alt)))
+
+	case *MathBlock:
+		r.buffer.WriteString("\n<p>")
+		r.renderMath(n.TeX, true)
+		r.buffer.WriteString("</p>\n")
+
+	case *HTMLBlock:
+		switch r.opts.HTML {
+		case HTMLUnsafe:
+			r.buffer.WriteString("\n")
+			r.buffer.Write(n.HTML)
+			r.buffer.WriteString("\n")
+
+		case HTMLEscape:
+			r.buffer.WriteString("\n<p>")
+			r.writeEscaped(n.HTML)
+			r.buffer.WriteString("</p>\n")
+
+		default:
+			r.buffer.WriteString("\n")
+			r.buffer.Write(sanitizeHTML(n.HTML, &r.opts))
+			r.buffer.WriteString("\n")
+		}
+
+	case *CodeBlock:
+		if r.opts.Highlight != nil && r.renderHighlight(n) {
+			return
+		}
+
+		if n.Lang != "" {
+			r.buffer.WriteString(fmt.Sprintf("\n<pre lang=\"%s\">\n<code>\n", escapeHTML([]byte(n.Lang))))
+		} else {
+			r.buffer.WriteString("\n<pre>\n<code>\n")
+		}
+		r.writeEscaped(n.Code)
+		r.buffer.WriteString("\n</code>\n</pre>\n")
+
+	case *Diagram:
+		r.renderDiagram(n)
+
+	case *ThematicBreak:
+		r.buffer.WriteString("\n<hr>\n")
+
+	case *Quote:
+		tight := r.tight
+		r.tight = false
+		r.buffer.WriteString("\n<blockquote>")
+		r.renderBlocks(n.Blocks)
+		r.buffer.WriteString("</blockquote>\n")
+		r.tight = tight
+
+	case *Admonition:
+		r.renderAdmonition(n)
+
+	case *List:
+		r.renderList(n)
+
+	case *Table:
+		r.renderTable(n)
+
+	case *DefinitionList:
+		r.renderDefinitionList(n)
+
+	case *Text:
+		r.writeText(n.Text)
+
+	case *CodeSpan:
+		r.settle(n.Code)
+		r.buffer.WriteString("<code>")
+		r.writeEscaped(n.Code)
+		r.buffer.WriteString("</code>")
+
+	case *Emphasis:
+		tag := "em"
+		if n.Level == 2 {
+			tag = "strong"
+		}
+		r.buffer.WriteString("<" + tag + ">")
+		r.renderBlocks(n.Inlines)
+		r.buffer.WriteString("</" + tag + ">")
+
+	case *Strikethrough:
+		r.buffer.WriteString("<del>")
+		r.renderBlocks(n.Inlines)
+		r.buffer.WriteString("</del>")
+
+	case *Link:
+		if !SafeURL(n.Dest, false) {
+			r.renderBlocks(n.Inlines)
+			return
+		}
+		r.buffer.WriteString(fmt.Sprintf("<a href=\"%s\"", escapeHTML([]byte(normalizeURL(n.Dest)))))
+		if n.Title != "" {
+			r.buffer.WriteString(fmt.Sprintf(" title=\"%s\"", escapeHTML([]byte(n.Title))))
+		}
+		r.buffer.WriteString(">")
+		// the text of an autolink is the url, which must stay as it is
+		off := r.typo.off
+		r.typo.off = off || n.auto || plainText(n.Inlines) == n.Dest
+		r.renderBlocks(n.Inlines)
+		r.typo.off = off
+		r.buffer.WriteString("</a>")
+
+	case *InlineImage:
+		alt := escapeHTML([]byte(plainText(n.Inlines)))
+		if !SafeURL(n.Dest, true) {
+			r.buffer.Write(alt)
+			return
+		}
+		r.buffer.WriteString(fmt.Sprintf("<img src=\"%s\" alt=\"%s\"", escapeHTML([]byte(normalizeURL(n.Dest))), alt))
+		if n.Title != "" {
+			r.buffer.WriteString(fmt.Sprintf(" title=\"%s\"", escapeHTML([]byte(n.Title))))
+		}
+		r.buffer.WriteString(">")
+
+	case *RawHTML:
+		r.buffer.Write(rawHTML(n.HTML, reHTMLTag.FindSubmatchIndex(n.HTML), &r.opts))
+
+	case *Abbreviation:
+		r.renderAbbreviation(n)
+
+	case *Math:
+		r.settle(n.TeX)
+		r.renderMath(n.TeX, n.Display)
+
+	case *FootnoteRef:
+		r.buffer.Write(r.footnoteRef(n.Label))
+
+	case *SoftBreak:
+		r.typo.prev = '\n'
+		r.buffer.WriteString("\n")
+
+	case *HardBreak:
+		r.typo.prev = '\n'
+		r.buffer.WriteString("<br>\n")
+	}
+}
+
+// flush writes the buffer to out when the document is streamed, the
+// buffer is then reused for the next block.
+func (r *htmlRenderer) flush() {
+	if r.out == nil || r.err != nil {
+		return
+	}
+
+	_, r.err = r.out.Write(r.buffer.Bytes())
+	r.buffer.Reset()
+}
+
+// writeEscaped escapes text into the buffer, the runs without special
+// characters are copied at once.
+func (r *htmlRenderer) writeEscaped(text []byte) {
+	start := 0
+	for i, c := range text {
+		switch c {
+		case '&', '<', '>', '"':
+			r.buffer.Write(text[start:i])
+			writeEscaped(r.buffer, c)
+			start = i + 1
+		}
+	}
+	r.buffer.Write(text[start:])
+}
+
+func (r *htmlRenderer) renderBlocks(blocks []Node) {
+	for _, block := range blocks {
+		r.render(block)
+	}
+}
+
+func (r *htmlRenderer) renderList(l *List) {
+	tag := "ul"
+	if l.Ordered {
+		tag = "ol"
+	}
+
+	if l.Ordered && l.Start != 1 {
+		r.buffer.WriteString(fmt.Sprintf("\n<%s start=\"%d\">\n", tag, l.Start))
+	} else {
+		r.buffer.WriteString(fmt.Sprintf("\n<%s>\n", tag))
+	}
+
+	tight := r.tight
+	r.tight = l.Tight
+	for _, item := range l.Items {
+		if !item.Task {
+			r.buffer.WriteString("<li>")
+			r.renderBlocks(item.Blocks)
+			r.buffer.WriteString("</li>\n")
+			continue
+		}
+
+		// the checkbox goes into the first paragraph of the item
+		box := "<input type=\"checkbox\" disabled> "
+		if item.Checked {
+			box = "<input type=\"checkbox\" checked disabled> "
+		}
+		r.buffer.WriteString("<li class=\"task-list-item\">")
+		blocks := item.Blocks
+		if para, ok := blocks[0].(*Paragraph); ok && !r.tight {
+			r.buffer.WriteString("\n<p>" + box)
+			r.renderBlocks(para.Inlines)
+			r.buffer.WriteString("</p>\n")
+			blocks = blocks[1:]
+		} else {
+			r.buffer.WriteString(box)
+		}
+		r.renderBlocks(blocks)
+		r.buffer.WriteString("</li>\n")
+	}
+	r.tight = tight
+
+	r.buffer.WriteString(fmt.Sprintf("</%s>\n", tag))
+}
+
+func (r *htmlRenderer) renderTable(t *Table) {
+	r.buffer.WriteString("\n<table>\n")
+	for i, row := range t.Rows {
+		switch i {
+		case 0:
+			r.buffer.WriteString("<thead>\n")
+		case 1:
+			r.buffer.WriteString("<tbody>\n")
+		}
+
+		r.buffer.WriteString("<tr>\n")
+		for _, cell := range row.Cells {
+			tag := "td"
+			if cell.Header {
+				tag = "th"
+			}
+
+			if cell.Align != AlignNone {
+				r.buffer.WriteString(fmt.Sprintf("<%s align=\"%s\">", tag, cell.Align))
+			} else {
+				r.buffer.WriteString(fmt.Sprintf("<%s>", tag))
+			}
+			r.resetTypography()
+			r.renderBlocks(cell.Inlines)
+			r.buffer.WriteString(fmt.Sprintf("</%s>\n", tag))
+		}
+		r.buffer.WriteString("</tr>\n")
+
+		if i == 0 {
+			r.buffer.WriteString("</thead>\n")
+		}
+	}
+
+	if len(t.Rows) > 1 {
+		r.buffer.WriteString("</tbody>\n")
+	}
+	r.buffer.WriteString("</table>\n")
+}
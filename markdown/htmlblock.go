@@ -0,0 +1,131 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+var (
+	reHTMLBlockRaw     *regexp.Regexp
+	reHTMLBlockRawEnd  *regexp.Regexp
+	reHTMLBlockTag     *regexp.Regexp
+	reHTMLBlockOpenTag *regexp.Regexp
+)
+
+// htmlBlockNames are the tags which start an HTML block ended by a blank
+// line, the list is the one of the CommonMark spec.
+var htmlBlockNames = words(`address article aside base basefont blockquote body
+	caption center col colgroup dd details dialog dir div dl dt fieldset
+	figcaption figure footer form frame frameset h1 h2 h3 h4 h5 h6 head header
+	hr html iframe legend li link main menu menuitem nav noframes ol optgroup
+	option p param search section summary table tbody td tfoot th thead title tr
+	track ul`)
+
+// htmlBlockEnds are the end markers of the HTML blocks which may contain
+// blank lines, other blocks end at a blank line.
+var htmlBlockEnds = []string{"", "-->", "?>", ">", "]]>"}
+
+func init() {
+	attr := `[a-zA-Z_:][-a-zA-Z0-9_:.]*(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?`
+	reHTMLBlockRaw = regexp.MustCompile(`(?i)^ {0,3}<(pre|script|style|textarea)(?:[ \t>]|$)`)
+	reHTMLBlockRawEnd = regexp.MustCompile(`(?i)</(?:pre|script|style|textarea)>`)
+	reHTMLBlockTag = regexp.MustCompile(`^ {0,3}</?([a-zA-Z][a-zA-Z0-9-]*)(?:[ \t]|/?>|$)`)
+	reHTMLBlockOpenTag = regexp.MustCompile(`^ {0,3}(?:<[a-zA-Z][a-zA-Z0-9-]*(?:[ \t]+` + attr + `)*[ \t]*/?>|</[a-zA-Z][a-zA-Z0-9-]*[ \t]*>)[ \t]*$`)
+}
+
+// htmlBlockKind is the start condition of the HTML block which text opens,
+// numbered as in the CommonMark spec, or 0. Blocks of kind 7 can not
+// interrupt a paragraph.
+func htmlBlockKind(text []byte) int {
+	trimmed := bytes.TrimLeft(text, " ")
+	if len(text)-len(trimmed) > 3 || !bytes.HasPrefix(trimmed, []byte("<")) {
+		return 0
+	}
+
+	switch {
+	case reHTMLBlockRaw.Match(text):
+		return 1
+
+	case bytes.HasPrefix(trimmed, []byte("<!--")):
+		return 2
+
+	case bytes.HasPrefix(trimmed, []byte("<?")):
+		return 3
+
+	case len(trimmed) > 2 && trimmed[1] == '!' && isLetter(trimmed[2]):
+		return 4
+
+	case bytes.HasPrefix(trimmed, []byte("<![CDATA[")):
+		return 5
+	}
+
+	if ret := reHTMLBlockTag.FindSubmatch(text); ret != nil && htmlBlockNames[strings.ToLower(string(ret[1]))] {
+		return 6
+	}
+
+	if reHTMLBlockOpenTag.Match(text) {
+		return 7
+	}
+
+	return 0
+}
+
+// parseHTMLBlock consumes the lines of an HTML block up to its end marker,
+// or up to a blank line.
+func (p *blockParser) parseHTMLBlock() Node {
+	first := p.peek()
+	kind := htmlBlockKind(first.text)
+
+	var texts [][]byte
+	for p.pos < len(p.lines) {
+		text := p.peek().text
+		if kind >= 6 && isBlank(text) {
+			break
+		}
+		texts = append(texts, text)
+		p.pos++
+
+		if kind == 1 && reHTMLBlockRawEnd.Match(text) {
+			break
+		}
+		if kind > 1 && kind < 6 && bytes.Contains(text, []byte(htmlBlockEnds[kind-1])) {
+			break
+		}
+	}
+
+	return &HTMLBlock{
+		Position: Position{Line: first.num, Column: first.col + len(leadingSpace(first.text))},
+		HTML:     bytes.Join(texts, lineTrail),
+	}
+}
+
+// sanitizeHTML filters the tags of an HTML block through rawHTML, the text
+// between the tags is escaped except for entity references.
+func sanitizeHTML(input []byte, opts *Options) []byte {
+	var buffer bytes.Buffer
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; c {
+		case '<':
+			if ret := reHTMLTag.FindSubmatchIndex(input[i:]); ret != nil {
+				buffer.Write(rawHTML(input[i:], ret, opts))
+				i += ret[1] - 1
+				continue
+			}
+
+		case '&':
+			if ret := reEntity.Find(input[i:]); ret != nil {
+				buffer.Write(ret)
+				i += len(ret) - 1
+				continue
+			}
+		}
+		writeEscaped(&buffer, input[i])
+	}
+
+	return buffer.Bytes()
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
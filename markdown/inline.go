@@ -0,0 +1,1058 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	reLinkDef   *regexp.Regexp
+	reAutolink  *regexp.Regexp
+	reEmailLink *regexp.Regexp
+	reBareURL   *regexp.Regexp
+)
+
+func init() {
+	reLinkDef = regexp.MustCompile(`^ {0,3}\[`)
+	reAutolink = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.\-]{1,31}:[^\s<>\x00-\x1f]*)>`)
+	reEmailLink = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~\-]+@[a-zA-Z0-9](?:[a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?)*)>`)
+	reBareURL = regexp.MustCompile(`^(?:https?://|www\.)[^\s<\[\]]+`)
+}
+
+// inlineItem is an element of the linked list of nodes built by the
+// inline parser, delimiter runs are wrapped into emphasis in place.
+type inlineItem struct {
+	node       Node
+	prev, next *inlineItem
+}
+
+// delimiter is a run of `*`, `_` or `~` which may open or close emphasis.
+type delimiter struct {
+	item     *inlineItem
+	char     byte
+	count    int
+	length   int
+	offset   int
+	canOpen  bool
+	canClose bool
+
+	prev, next *delimiter
+}
+
+// bracket is a `[` or `![` which may start a link or an image.
+type bracket struct {
+	item   *inlineItem
+	image  bool
+	active bool
+	start  int
+	// delims is the top of the delimiter stack when the bracket was seen
+	delims *delimiter
+}
+
+// inlineParser parses the inline content of leaf blocks, following the
+// delimiter run algorithm of CommonMark. Links and footnote references are
+// resolved against the definitions of the document.
+type inlineParser struct {
+	ext       *Parser
+	links     map[string]*LinkDefinition
+	footnotes map[string]*FootnoteDefinition
+
+	input     []byte
+	pos       Position
+	head      *inlineItem
+	tail      *inlineItem
+	delims    *delimiter
+	brackets  []*bracket
+	text      []byte
+	textStart int
+}
+
+// parseInlines parses the inline content of every leaf block of doc.
+func parseInlines(doc *Document, ext *Parser) {
+	p := &inlineParser{
+		ext:       ext,
+		links:     collectLinks(doc),
+		footnotes: collectFootnotes(doc),
+	}
+
+	Walk(doc, func(node Node) bool {
+		switch n := node.(type) {
+		case *Paragraph:
+			n.Inlines = p.parse(n.Text, n.Position)
+		case *Heading:
+			n.Inlines = p.parse(n.Text, n.Position)
+		case *TableCell:
+			n.Inlines = p.parse(n.Text, n.Position)
+		case *DefinitionTerm:
+			n.Inlines = p.parse(n.Text, n.Position)
+		}
+		return true
+	})
+}
+
+func (p *inlineParser) parse(input []byte, pos Position) []Node {
+	p.input = input
+	p.pos = pos
+	p.head, p.tail = nil, nil
+	p.delims = nil
+	p.brackets = nil
+	p.text = nil
+
+	for i := 0; i < len(input); {
+		if end := p.textRun(i); end > i {
+			p.appendText(i, input[i:end]...)
+			i = end
+			continue
+		}
+
+		next := p.parseCustom(i)
+		if next != i {
+			i = next
+			continue
+		}
+
+		switch input[i] {
+		case '\\':
+			next = p.parseEscape(i)
+		case '`':
+			next = p.parseCodeSpan(i)
+		case '*', '_', '~':
+			next = p.parseDelimiterRun(i)
+		case '$':
+			next = p.parseMath(i)
+		case '[', '!':
+			next = p.parseOpenBracket(i)
+		case ']':
+			next = p.parseCloseBracket(i)
+		case '<':
+			next = p.parseAngle(i)
+		case '&':
+			next = p.parseEntity(i)
+		case '\n':
+			next = p.parseNewline(i)
+		case 'h', 'w':
+			next = p.parseBareURL(i)
+		}
+
+		if next == i {
+			p.appendText(i, input[i])
+			next++
+		}
+		i = next
+	}
+	p.flushText()
+	p.processEmphasis(nil)
+
+	return p.nodes(p.head, nil)
+}
+
+// inlineSpecial marks the bytes which may start inline syntax, `h` and `w`
+// only do so at the start of a word.
+var inlineSpecial = [256]bool{
+	'\\': true, '`': true, '*': true, '_': true, '~': true, '$': true,
+	'[': true, '!': true, ']': true, '<': true, '&': true, '\n': true,
+}
+
+// textRun is the end of the run of plain text from i, which the parse loop
+// copies at once.
+func (p *inlineParser) textRun(i int) int {
+	for ; i < len(p.input); i++ {
+		c := p.input[i]
+		if inlineSpecial[c] || p.ext != nil && p.ext.triggers[c] {
+			break
+		}
+		if (c == 'h' || c == 'w') && (i == 0 || strings.IndexByte(" \t\n*_~(", p.input[i-1]) >= 0) {
+			break
+		}
+	}
+
+	return i
+}
+
+func (p *inlineParser) position(offset int) Position {
+	return textPosition(p.pos, p.input, offset)
+}
+
+func (p *inlineParser) appendText(offset int, b ...byte) {
+	if len(p.text) == 0 {
+		p.textStart = offset
+	}
+	p.text = append(p.text, b...)
+}
+
+func (p *inlineParser) flushText() {
+	if len(p.text) == 0 {
+		return
+	}
+	p.append(&Text{Position: p.position(p.textStart), Text: p.text})
+	p.text = nil
+}
+
+func (p *inlineParser) append(node Node) *inlineItem {
+	item := &inlineItem{node: node, prev: p.tail}
+	if p.tail != nil {
+		p.tail.next = item
+	} else {
+		p.head = item
+	}
+	p.tail = item
+
+	return item
+}
+
+func (p *inlineParser) remove(item *inlineItem) {
+	if item.prev != nil {
+		item.prev.next = item.next
+	} else {
+		p.head = item.next
+	}
+	if item.next != nil {
+		item.next.prev = item.prev
+	} else {
+		p.tail = item.prev
+	}
+}
+
+// nodes collects the nodes from the item from up to the item to, adjacent
+// text nodes are merged.
+func (p *inlineParser) nodes(from, to *inlineItem) []Node {
+	var nodes []Node
+	var last *Text
+	for item := from; item != to; item = item.next {
+		text, ok := item.node.(*Text)
+		switch {
+		case ok && len(text.Text) == 0:
+			continue
+
+		case ok && last != nil:
+			last.Text = append(last.Text, text.Text...)
+			continue
+
+		case ok:
+			last = &Text{Position: text.Position, Text: append([]byte(nil), text.Text...)}
+			nodes = append(nodes, last)
+			continue
+		}
+
+		last = nil
+		nodes = append(nodes, item.node)
+	}
+
+	return nodes
+}
+
+func (p *inlineParser) parseEscape(i int) int {
+	if i+1 < len(p.input) && p.input[i+1] == '\n' {
+		p.flushText()
+		p.append(&HardBreak{Position: p.position(i)})
+		return skipLeadingSpace(p.input, i+2)
+	}
+
+	if i+1 < len(p.input) && isASCIIPunct(p.input[i+1]) {
+		p.appendText(i, p.input[i+1])
+		return i + 2
+	}
+
+	return i
+}
+
+// parseCodeSpan matches a backtick run with the next run of the same
+// length, the content is not parsed any further.
+func (p *inlineParser) parseCodeSpan(i int) int {
+	n := runLength(p.input, i)
+	for j := i + n; j < len(p.input); {
+		if p.input[j] != '`' {
+			j++
+			continue
+		}
+
+		m := runLength(p.input, j)
+		if m != n {
+			j += m
+			continue
+		}
+
+		code := bytes.Replace(p.input[i+n:j], lineTrail, []byte(" "), -1)
+		if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' && len(bytes.Trim(code, " ")) > 0 {
+			code = code[1 : len(code)-1]
+		}
+		p.flushText()
+		p.append(&CodeSpan{Position: p.position(i), Code: code})
+		return j + n
+	}
+
+	p.appendText(i, p.input[i:i+n]...)
+	return i + n
+}
+
+// parseDelimiterRun pushes a run of `*`, `_` or `~` to the delimiter stack,
+// whether it can open or close emphasis depends on the characters around it.
+func (p *inlineParser) parseDelimiterRun(i int) int {
+	c := p.input[i]
+	n := runLength(p.input, i)
+	if c == '~' && n > 2 {
+		p.appendText(i, p.input[i:i+n]...)
+		return i + n
+	}
+
+	before, after := ' ', ' '
+	if i > 0 {
+		before, _ = utf8.DecodeLastRune(p.input[:i])
+	}
+	if i+n < len(p.input) {
+		after, _ = utf8.DecodeRune(p.input[i+n:])
+	}
+
+	left := !unicode.IsSpace(after) && (!isPunct(after) || unicode.IsSpace(before) || isPunct(before))
+	right := !unicode.IsSpace(before) && (!isPunct(before) || unicode.IsSpace(after) || isPunct(after))
+	canOpen, canClose := left, right
+	if c == '_' {
+		canOpen = left && (!right || isPunct(before))
+		canClose = right && (!left || isPunct(after))
+	}
+
+	p.flushText()
+	item := p.append(&Text{Position: p.position(i), Text: append([]byte(nil), p.input[i:i+n]...)})
+	if canOpen || canClose {
+		d := &delimiter{
+			item:     item,
+			char:     c,
+			count:    n,
+			length:   n,
+			offset:   i,
+			canOpen:  canOpen,
+			canClose: canClose,
+			prev:     p.delims,
+		}
+		if p.delims != nil {
+			p.delims.next = d
+		}
+		p.delims = d
+	}
+
+	return i + n
+}
+
+// parseOpenBracket handles `[` and `![`, a `[^label]` of a defined
+// footnote is a footnote reference.
+func (p *inlineParser) parseOpenBracket(i int) int {
+	image := p.input[i] == '!'
+	if image && (i+1 == len(p.input) || p.input[i+1] != '[') {
+		return i
+	}
+
+	if !image {
+		if ret := reFootnoteRef.FindSubmatchIndex(p.input[i:]); ret != nil && ret[0] == 0 {
+			label := footnoteLabel(p.input[i+ret[2] : i+ret[3]])
+			if _, ok := p.footnotes[label]; ok {
+				p.flushText()
+				p.append(&FootnoteRef{Position: p.position(i), Label: label})
+				return i + ret[1]
+			}
+		}
+	}
+
+	start := i + 1
+	if image {
+		start++
+	}
+	p.flushText()
+	item := p.append(&Text{Position: p.position(i), Text: append([]byte(nil), p.input[i:start]...)})
+	p.brackets = append(p.brackets, &bracket{
+		item:   item,
+		image:  image,
+		active: true,
+		start:  start,
+		delims: p.delims,
+	})
+
+	return start
+}
+
+// parseCloseBracket turns the text since the last bracket into a link or
+// an image, if the bracket is followed by a destination or a defined
+// reference.
+func (p *inlineParser) parseCloseBracket(i int) int {
+	if len(p.brackets) == 0 {
+		return i
+	}
+
+	b := p.brackets[len(p.brackets)-1]
+	p.brackets = p.brackets[:len(p.brackets)-1]
+	if !b.active {
+		return i
+	}
+
+	dest, title, end, ok := p.parseLinkTail(i + 1)
+	if !ok {
+		label := p.input[b.start:i]
+		end = i + 1
+		if ref, next, found := parseLinkLabel(p.input, i+1); found {
+			if len(ref) > 0 {
+				label = ref
+			}
+			end = next
+		}
+
+		var def *LinkDefinition
+		if len(label) <= 999 {
+			def, ok = p.links[linkLabel(label)]
+		}
+		if !ok {
+			return i
+		}
+		dest, title = def.URL, def.Title
+	}
+
+	p.flushText()
+	p.processEmphasis(b.delims)
+	inlines := p.nodes(b.item.next, nil)
+	p.tail = b.item
+
+	var node Node
+	if b.image {
+		node = &InlineImage{Position: b.item.node.Pos(), Dest: dest, Title: title, Inlines: inlines}
+	} else {
+		node = &Link{Position: b.item.node.Pos(), Dest: dest, Title: title, Inlines: unwrapAutolinks(inlines)}
+		// links may not contain other links
+		for _, opener := range p.brackets {
+			if !opener.image {
+				opener.active = false
+			}
+		}
+	}
+	b.item.node = node
+	b.item.next = nil
+
+	return end
+}
+
+// parseLinkTail parses the `(destination "title")` after the text of an
+// inline link.
+func (p *inlineParser) parseLinkTail(i int) (dest, title string, end int, ok bool) {
+	if i >= len(p.input) || p.input[i] != '(' {
+		return "", "", 0, false
+	}
+
+	i = skipSpace(p.input, i+1)
+	raw, next, ok := parseLinkDestination(p.input, i)
+	if !ok {
+		return "", "", 0, false
+	}
+	dest = unescapeString(raw)
+
+	i = skipSpace(p.input, next)
+	if i > next {
+		if raw, next, found := parseLinkTitle(p.input, i); found {
+			title = unescapeString(raw)
+			i = skipSpace(p.input, next)
+		}
+	}
+
+	if i >= len(p.input) || p.input[i] != ')' {
+		return "", "", 0, false
+	}
+
+	return dest, title, i + 1, true
+}
+
+// parseAngle handles autolinks such as `<http://...>` and raw HTML tags.
+func (p *inlineParser) parseAngle(i int) int {
+	if ret := reAutolink.FindSubmatch(p.input[i:]); ret != nil {
+		p.flushText()
+		p.append(&Link{
+			Position: p.position(i),
+			Dest:     string(ret[1]),
+			Inlines:  []Node{&Text{Position: p.position(i + 1), Text: ret[1]}},
+		})
+		return i + len(ret[0])
+	}
+
+	if ret := reEmailLink.FindSubmatch(p.input[i:]); ret != nil {
+		p.flushText()
+		p.append(&Link{
+			Position: p.position(i),
+			Dest:     "mailto:" + string(ret[1]),
+			Inlines:  []Node{&Text{Position: p.position(i + 1), Text: ret[1]}},
+		})
+		return i + len(ret[0])
+	}
+
+	// closing tags have no attributes
+	ret := reHTMLTag.FindSubmatchIndex(p.input[i:])
+	if ret != nil && (ret[3] == ret[2] || (ret[7] == ret[6] && ret[9] == ret[8])) {
+		p.flushText()
+		p.append(&RawHTML{Position: p.position(i), HTML: p.input[i : i+ret[1]]})
+		return i + ret[1]
+	}
+
+	return i
+}
+
+func (p *inlineParser) parseEntity(i int) int {
+	ret := reEntity.Find(p.input[i:])
+	if ret == nil {
+		return i
+	}
+
+	p.appendText(i, []byte(html.UnescapeString(string(ret)))...)
+	return i + len(ret)
+}
+
+// parseNewline ends a line of the paragraph, the spaces around the line
+// ending are dropped. Two or more spaces before it make a hard break.
+func (p *inlineParser) parseNewline(i int) int {
+	hard := bytes.HasSuffix(p.text, []byte("  "))
+	p.text = bytes.TrimRight(p.text, " ")
+	p.flushText()
+	if hard {
+		p.append(&HardBreak{Position: p.position(i)})
+	} else {
+		p.append(&SoftBreak{Position: p.position(i)})
+	}
+
+	return skipLeadingSpace(p.input, i+1)
+}
+
+func skipLeadingSpace(input []byte, i int) int {
+	for i < len(input) && input[i] == ' ' {
+		i++
+	}
+
+	return i
+}
+
+// parseBareURL links a http, https or www url at the start of a word, the
+// trailing punctuation is not part of the url.
+func (p *inlineParser) parseBareURL(i int) int {
+	if i > 0 && !strings.ContainsRune(" \t\n*_~(", rune(p.input[i-1])) {
+		return i
+	}
+
+	ret := reBareURL.Find(p.input[i:])
+	if ret == nil {
+		return i
+	}
+
+	url := trimURL(ret)
+	if bytes.HasSuffix(url, []byte("//")) || bytes.Equal(url, []byte("www.")) {
+		return i
+	}
+
+	dest := string(url)
+	if bytes.HasPrefix(url, []byte("www.")) {
+		dest = "http://" + dest
+	}
+	p.flushText()
+	p.append(&Link{
+		Position: p.position(i),
+		Dest:     dest,
+		Inlines:  []Node{&Text{Position: p.position(i), Text: url}},
+		auto:     true,
+	})
+
+	return i + len(url)
+}
+
+// processEmphasis matches the openers and closers of the delimiter stack
+// above bottom, and wraps the nodes between them into emphasis.
+func (p *inlineParser) processEmphasis(bottom *delimiter) {
+	type openerKey struct {
+		char    byte
+		canOpen bool
+		length  int
+	}
+	openersBottom := make(map[openerKey]*delimiter)
+
+	closer := p.delims
+	for closer != nil && closer.prev != bottom {
+		closer = closer.prev
+	}
+
+	for closer != nil {
+		if !closer.canClose {
+			closer = closer.next
+			continue
+		}
+
+		key := openerKey{char: closer.char, canOpen: closer.canOpen, length: closer.length % 3}
+		floor, ok := openersBottom[key]
+		if !ok {
+			floor = bottom
+		}
+
+		var opener *delimiter
+		for d := closer.prev; d != nil && d != bottom && d != floor; d = d.prev {
+			if d.canOpen && d.char == closer.char && matchDelimiters(d, closer) {
+				opener = d
+				break
+			}
+		}
+
+		if opener == nil {
+			openersBottom[key] = closer.prev
+			next := closer.next
+			if !closer.canOpen {
+				p.removeDelimiter(closer)
+			}
+			closer = next
+			continue
+		}
+
+		n := 1
+		if opener.count >= 2 && closer.count >= 2 {
+			n = 2
+		}
+		opener.count -= n
+		closer.count -= n
+		open := opener.item.node.(*Text)
+		open.Text = open.Text[:opener.count]
+		close := closer.item.node.(*Text)
+		close.Text = close.Text[:closer.count]
+
+		var node Node
+		pos := p.position(opener.offset + opener.count)
+		inlines := p.nodes(opener.item.next, closer.item)
+		if closer.char == '~' {
+			node = &Strikethrough{Position: pos, Inlines: inlines}
+		} else {
+			node = &Emphasis{Position: pos, Level: n, Inlines: inlines}
+		}
+		item := &inlineItem{node: node, prev: opener.item, next: closer.item}
+		opener.item.next = item
+		closer.item.prev = item
+
+		// the delimiters between the opener and the closer are unmatched
+		opener.next = closer
+		closer.prev = opener
+
+		if opener.count == 0 {
+			p.remove(opener.item)
+			p.removeDelimiter(opener)
+		}
+		if closer.count == 0 {
+			next := closer.next
+			p.remove(closer.item)
+			p.removeDelimiter(closer)
+			closer = next
+		}
+	}
+
+	p.delims = bottom
+	if bottom != nil {
+		bottom.next = nil
+	}
+}
+
+// matchDelimiters applies the rule of three to `*` and `_`, strikethrough
+// needs runs of the same length.
+func matchDelimiters(opener, closer *delimiter) bool {
+	if closer.char == '~' {
+		return opener.count == closer.count
+	}
+
+	if (opener.canClose || closer.canOpen) && (opener.length+closer.length)%3 == 0 {
+		return opener.length%3 == 0 && closer.length%3 == 0
+	}
+
+	return true
+}
+
+func (p *inlineParser) removeDelimiter(d *delimiter) {
+	if d.prev != nil {
+		d.prev.next = d.next
+	}
+	if d.next != nil {
+		d.next.prev = d.prev
+	} else {
+		p.delims = d.prev
+	}
+}
+
+// unwrapAutolinks replaces the bare urls inside of a link by their text.
+func unwrapAutolinks(nodes []Node) []Node {
+	var result []Node
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *Link:
+			if n.auto {
+				result = append(result, n.Inlines...)
+				continue
+			}
+		case *Emphasis:
+			n.Inlines = unwrapAutolinks(n.Inlines)
+		case *Strikethrough:
+			n.Inlines = unwrapAutolinks(n.Inlines)
+		}
+		result = append(result, node)
+	}
+
+	return result
+}
+
+// isLinkDefinition reports whether the paragraph at index i starts with a
+// `[id]: url "title"` definition, which is kept for parseLinkDefinition.
+func (p *blockParser) isLinkDefinition(i int) bool {
+	if !reLinkDef.Match(p.lines[i].text) {
+		return false
+	}
+
+	def, n := p.scanLinkDefinition(i)
+	p.linkDef, p.linkDefAt, p.linkDefLines = def, i, n
+	return def != nil
+}
+
+// parseLinkDefinition consumes one link definition, which may span the
+// lines of a paragraph.
+func (p *blockParser) parseLinkDefinition() Node {
+	def, n := p.linkDef, p.linkDefLines
+	if def == nil || p.linkDefAt != p.pos {
+		def, n = p.scanLinkDefinition(p.pos)
+	}
+	p.linkDef = nil
+	p.pos += n
+
+	return def
+}
+
+// maxLabel is the length of the longest label of a link definition.
+const maxLabel = 999
+
+// scanLinkDefinition parses the definition at the start of the paragraph at
+// index i, it returns the definition and the number of its lines. The lines
+// of the paragraph are read as far as the definition needs, doubling them
+// each time, so a run of definitions is not scanned to its end for each.
+func (p *blockParser) scanLinkDefinition(i int) (*LinkDefinition, int) {
+	var texts [][]byte
+	for n := 1; ; n *= 2 {
+		complete := false
+		for j := i + len(texts); len(texts) < n; j++ {
+			if j >= len(p.lines) || isBlank(p.lines[j].text) || j > i && p.interrupts(j) {
+				complete = true
+				break
+			}
+			texts = append(texts, bytes.TrimLeft(p.lines[j].text, " \t"))
+		}
+
+		def, lines, more := p.linkDefinition(i, bytes.Join(texts, lineTrail), complete)
+		if !more {
+			return def, lines
+		}
+	}
+}
+
+// linkDefinition parses the definition at the start of input, the first
+// lines of the paragraph at index i, or all of them if complete. It
+// reports more if the definition may go on in the lines after input.
+func (p *blockParser) linkDefinition(i int, input []byte, complete bool) (*LinkDefinition, int, bool) {
+	l := p.lines[i]
+
+	label, pos, ok := parseLinkLabel(input, 0)
+	if !ok || pos >= len(input) {
+		return nil, 0, !complete && len(input) <= maxLabel+1
+	}
+	if len(bytes.TrimSpace(label)) == 0 || input[pos] != ':' {
+		return nil, 0, false
+	}
+
+	pos = skipLineSpace(input, pos+1)
+	if !complete && pos == len(input) {
+		// the destination may be on the next line
+		return nil, 0, true
+	}
+	start := pos
+	dest, pos, ok := parseLinkDestination(input, pos)
+	if !ok || pos == start {
+		return nil, 0, false
+	}
+	if !complete && bytes.IndexByte(input[pos:], '\n') < 0 {
+		// the title may be on the next line
+		return nil, 0, true
+	}
+
+	def := &LinkDefinition{
+		Position: Position{Line: l.num, Column: l.col + len(leadingSpace(l.text))},
+		Label:    linkLabel(label),
+		URL:      unescapeString(dest),
+	}
+
+	// the title must be separated by whitespace and end its line
+	end := pos
+	if next := skipLineSpace(input, pos); next > pos {
+		if title, after, ok := parseLinkTitle(input, next); ok {
+			if after = skipLineSpace(input, after); after == len(input) || input[after-1] == '\n' {
+				def.Title = unescapeString(title)
+				end = after
+			}
+		} else if !complete && unclosedTitle(input, next) {
+			return nil, 0, true
+		}
+	}
+	if end == pos {
+		end = skipLineSpace(input, pos)
+		if end < len(input) && input[end-1] != '\n' {
+			return nil, 0, false
+		}
+	}
+
+	return def, bytes.Count(bytes.TrimSuffix(input[:end], lineTrail), lineTrail) + 1, false
+}
+
+// unclosedTitle reports whether the link title at input[i] runs to the end
+// of input without its closing delimiter.
+func unclosedTitle(input []byte, i int) bool {
+	closing := input[i]
+	switch closing {
+	case '(':
+		closing = ')'
+	case '"', '\'':
+	default:
+		return false
+	}
+
+	for j := i + 1; j < len(input); j++ {
+		switch c := input[j]; {
+		case c == '\\':
+			j++
+		case c == closing, c == '(' && closing == ')':
+			return false
+		}
+	}
+
+	return true
+}
+
+// skipLineSpace skips spaces and tabs starting at input[i], including at
+// most one line ending.
+func skipLineSpace(input []byte, i int) int {
+	for i < len(input) && (input[i] == ' ' || input[i] == '\t') {
+		i++
+	}
+	if i < len(input) && input[i] == '\n' {
+		i++
+		for i < len(input) && (input[i] == ' ' || input[i] == '\t') {
+			i++
+		}
+	}
+
+	return i
+}
+
+// linkLabel normalizes a reference label, labels match case-insensitively
+// and with whitespace collapsed.
+func linkLabel(label []byte) string {
+	return strings.ToLower(strings.Join(strings.Fields(string(label)), " "))
+}
+
+// collectLinks maps the labels of doc to their definitions, the first
+// definition of a label wins.
+func collectLinks(doc *Document) map[string]*LinkDefinition {
+	links := make(map[string]*LinkDefinition)
+	Walk(doc, func(node Node) bool {
+		if def, ok := node.(*LinkDefinition); ok {
+			if _, exist := links[def.Label]; !exist {
+				links[def.Label] = def
+			}
+		}
+		return true
+	})
+
+	return links
+}
+
+// parseLinkDestination parses a `<...>` or a bare destination starting at
+// input[i], the parentheses of a bare destination must be balanced.
+func parseLinkDestination(input []byte, i int) ([]byte, int, bool) {
+	if i < len(input) && input[i] == '<' {
+		for j := i + 1; j < len(input); j++ {
+			switch input[j] {
+			case '\\':
+				j++
+			case '\n', '<':
+				return nil, 0, false
+			case '>':
+				return input[i+1 : j], j + 1, true
+			}
+		}
+		return nil, 0, false
+	}
+
+	depth := 0
+	j := i
+loop:
+	for ; j < len(input); j++ {
+		c := input[j]
+		switch {
+		case c == '\\' && j+1 < len(input) && isASCIIPunct(input[j+1]):
+			j++
+		case c == '(':
+			depth++
+			if depth > 32 {
+				return nil, 0, false
+			}
+		case c == ')':
+			if depth == 0 {
+				break loop
+			}
+			depth--
+		case c <= ' ' || c == 0x7f:
+			break loop
+		}
+	}
+	if depth != 0 {
+		return nil, 0, false
+	}
+
+	return input[i:j], j, true
+}
+
+func parseLinkTitle(input []byte, i int) ([]byte, int, bool) {
+	if i >= len(input) {
+		return nil, 0, false
+	}
+
+	closing := input[i]
+	switch closing {
+	case '(':
+		closing = ')'
+	case '"', '\'':
+	default:
+		return nil, 0, false
+	}
+
+	for j := i + 1; j < len(input); j++ {
+		switch c := input[j]; {
+		case c == '\\':
+			j++
+		case c == closing:
+			return input[i+1 : j], j + 1, true
+		case c == '(' && closing == ')':
+			return nil, 0, false
+		}
+	}
+
+	return nil, 0, false
+}
+
+// parseLinkLabel parses the `[label]` of a reference link at input[i].
+func parseLinkLabel(input []byte, i int) ([]byte, int, bool) {
+	if i >= len(input) || input[i] != '[' {
+		return nil, 0, false
+	}
+
+	for j := i + 1; j < len(input); j++ {
+		switch input[j] {
+		case '\\':
+			j++
+		case '[':
+			return nil, 0, false
+		case ']':
+			return input[i+1 : j], j + 1, true
+		}
+	}
+
+	return nil, 0, false
+}
+
+// unescapeString decodes the backslash escapes and entity references of a
+// link destination or title.
+func unescapeString(input []byte) string {
+	var builder strings.Builder
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case c == '\\' && i+1 < len(input) && isASCIIPunct(input[i+1]):
+			i++
+			builder.WriteByte(input[i])
+
+		case c == '&':
+			if ret := reEntity.Find(input[i:]); ret != nil {
+				builder.WriteString(html.UnescapeString(string(ret)))
+				i += len(ret) - 1
+				continue
+			}
+			builder.WriteByte(c)
+
+		default:
+			builder.WriteByte(c)
+		}
+	}
+
+	return builder.String()
+}
+
+// trimURL drops the trailing punctuation and entity references of a bare
+// url, and a closing parenthesis which is not balanced in the url.
+func trimURL(url []byte) []byte {
+	for {
+		trimmed := bytes.TrimRight(url, ".,:;!?'\"*_~")
+		if i := bytes.LastIndexByte(trimmed, '&'); i >= 0 && reEntity.Match(trimmed[i:]) && trimmed[len(trimmed)-1] == ';' {
+			trimmed = trimmed[:i]
+		}
+		if bytes.HasSuffix(trimmed, []byte(")")) && bytes.Count(trimmed, []byte("(")) < bytes.Count(trimmed, []byte(")")) {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if len(trimmed) == len(url) {
+			return url
+		}
+		url = trimmed
+	}
+}
+
+// plainText is the text of nodes without markup, it is used for the alt
+// text of images and the titles of headings.
+func plainText(nodes []Node) string {
+	var builder strings.Builder
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *Text:
+			builder.Write(n.Text)
+		case *CodeSpan:
+			builder.Write(n.Code)
+		case *Abbreviation:
+			builder.Write(n.Text)
+		case *Math:
+			builder.Write(n.TeX)
+		case *SoftBreak, *HardBreak:
+			builder.WriteByte('\n')
+		case *FootnoteRef, *RawHTML:
+		default:
+			builder.WriteString(plainText(n.Children()))
+		}
+	}
+
+	return builder.String()
+}
+
+func runLength(input []byte, i int) int {
+	n := 0
+	for i+n < len(input) && input[i+n] == input[i] {
+		n++
+	}
+
+	return n
+}
+
+func skipSpace(input []byte, i int) int {
+	for i < len(input) && isSpace(input[i]) {
+		i++
+	}
+
+	return i
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isPunct(r rune) bool {
+	if r < utf8.RuneSelf {
+		return isASCIIPunct(byte(r))
+	}
+
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -0,0 +1,80 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestReferenceLink(t *testing.T) {
+	input := []string{
+		"see [the blog][Blog] and [Blog][]\n\n[blog]: http://hackcv.com \"Home\"",
+		"[Go]\n\n> [go]:  <https://go.dev>",
+		"![logo][img] [missing][none]\n\n[img]: /logo.png",
+		"[bad][x]\n\n[x]: javascript:alert(1)",
+		"[a]: /first\n[a]: /second\n\n[a]",
+		"[a]:\n/url\n'a\nmulti\nline'\n[b]: /b\n\n[a] [b]",
+		"[a]: /url\n\"open\n\n[a]",
+	}
+
+	output := []string{
+		"\n<p>see <a href=\"http://hackcv.com\" title=\"Home\">the blog</a> and <a href=\"http://hackcv.com\" title=\"Home\">Blog</a></p>\n",
+		"\n<p><a href=\"https://go.dev\">Go</a></p>\n\n<blockquote></blockquote>\n",
+		"\n<p><img src=\"/logo.png\" alt=\"logo\"> [missing][none]</p>\n",
+		"\n<p>bad</p>\n",
+		"\n<p><a href=\"/first\">a</a></p>\n",
+		"\n<p><a href=\"/url\" title=\"a\nmulti\nline\">a</a> <a href=\"/b\">b</a></p>\n",
+		"\n<p>&quot;open</p>\n\n<p><a href=\"/url\">a</a></p>\n",
+	}
+
+	for i, v := range input {
+		result := Render([]byte(v))
+		if !bytes.Equal(result, []byte(output[i])) {
+			t.Fatalf("ReferenceLink fail, [%s] vs [%s]", string(result), output[i])
+		}
+	}
+}
+
+func TestConsecutiveLinkDefinitions(t *testing.T) {
+	var input bytes.Buffer
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&input, "[l%d]: /%d \"title %d\"\n", i, i, i)
+	}
+	input.WriteString("\n[l0] [l4999]")
+
+	output := "\n<p><a href=\"/0\" title=\"title 0\">l0</a> <a href=\"/4999\" title=\"title 4999\">l4999</a></p>\n"
+	if result := Render(input.Bytes()); string(result) != output {
+		t.Fatalf("consecutive link definitions fail, [%s] vs [%s]", result, output)
+	}
+}
+
+func TestParseImageTitle(t *testing.T) {
+	input := "![logo](</my logo.png> \"The logo\")"
+	output := "\n<img src=\"/my%20logo.png\" alt=\"logo\" title=\"The logo\">\n"
+
+	result := Render([]byte(input))
+	if !bytes.Equal(result, []byte(output)) {
+		t.Fatalf("ParseImageTitle fail, [%s] vs [%s]", string(result), output)
+	}
+}
+
+func TestParseInline(t *testing.T) {
+	doc := Parse([]byte("a *b `c_d`* [e](/f)"))
+	para := doc.Blocks[0].(*Paragraph)
+	if len(para.Inlines) != 4 {
+		t.Fatalf("ParseInline fail, %d inlines vs 4", len(para.Inlines))
+	}
+
+	em, ok := para.Inlines[1].(*Emphasis)
+	if !ok || em.Level != 1 || em.Column != 3 || len(em.Inlines) != 2 {
+		t.Fatalf("ParseInline fail, invalid emphasis %+v", para.Inlines[1])
+	}
+	if code, ok := em.Inlines[1].(*CodeSpan); !ok || string(code.Code) != "c_d" {
+		t.Fatalf("ParseInline fail, invalid code span %+v", em.Inlines[1])
+	}
+
+	link, ok := para.Inlines[3].(*Link)
+	if !ok || link.Dest != "/f" || link.Column != 13 || plainText(link.Inlines) != "e" {
+		t.Fatalf("ParseInline fail, invalid link %+v", para.Inlines[3])
+	}
+}
@@ -0,0 +1,113 @@
+package markdown
+
+import (
+	"strings"
+)
+
+var (
+	goLexer = &lexer{
+		keywords: words(`break case chan const continue default defer else fallthrough for
+			func go goto if import interface map package range return select struct switch type var`),
+		builtins: words(`append bool byte cap close complex complex64 complex128 copy delete error
+			false float32 float64 imag int int8 int16 int32 int64 iota len make max min new nil
+			panic print println real recover rune string true uint uint8 uint16 uint32 uint64 uintptr any`),
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		strings:       []string{`"`, "'", "`"},
+		rawStrings:    map[string]bool{"`": true},
+		functions:     true,
+	}
+
+	pythonLexer = &lexer{
+		keywords: words(`False None True and as assert async await break class continue def del
+			elif else except finally for from global if import in is lambda nonlocal not or pass
+			raise return try while with yield`),
+		builtins: words(`abs all any bool bytes dict dir enumerate filter float format getattr
+			hasattr id input int isinstance iter len list map max min next object open print range
+			repr reversed round self set setattr sorted str sum super tuple type zip`),
+		lineComments: []string{"#"},
+		strings:      []string{`"""`, `'''`, `"`, "'"},
+		functions:    true,
+	}
+
+	shellLexer = &lexer{
+		keywords: words(`case do done elif else esac fi for function if in select then until while`),
+		builtins: words(`alias bg cd declare echo eval exec exit export fg getopts hash kill let
+			local printf pwd read readonly return set shift source test trap type ulimit umask
+			unalias unset wait`),
+		lineComments: []string{"#"},
+		strings:      []string{`"`, "'"},
+		rawStrings:   map[string]bool{"'": true},
+		wordComments: true,
+		variables:    true,
+	}
+
+	javascriptLexer = &lexer{
+		keywords: words(`async await break case catch class const continue debugger default delete
+			do else export extends finally for function if import in instanceof let new of return
+			static super switch this throw try typeof var void while with yield`),
+		builtins: words(`Array Boolean Date Error JSON Map Math Number Object Promise RegExp Set
+			String Symbol console document false null true undefined window`),
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		strings:       []string{`"`, "'", "`"},
+		rawStrings:    map[string]bool{"`": true},
+		functions:     true,
+	}
+
+	cLexer = &lexer{
+		keywords: words(`auto break case catch class const continue default delete do else enum
+			extern for goto if inline namespace new private protected public register return
+			sizeof static struct switch template this throw try typedef union using virtual
+			volatile while`),
+		builtins: words(`bool char double false float int long nullptr short signed size_t std
+			true unsigned void NULL`),
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		strings:       []string{`"`, "'"},
+		preproc:       true,
+		functions:     true,
+	}
+
+	jsonLexer = &lexer{
+		keywords: words(`true false null`),
+		strings:  []string{`"`},
+	}
+
+	// genericLexer highlights strings, numbers and the common comment forms
+	// of languages without a lexer.
+	genericLexer = &lexer{
+		lineComments:  []string{"//", "#"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		strings:       []string{`"`, "'"},
+		wordComments:  true,
+	}
+)
+
+var lexers = map[string]*lexer{
+	"go":         goLexer,
+	"golang":     goLexer,
+	"python":     pythonLexer,
+	"py":         pythonLexer,
+	"python3":    pythonLexer,
+	"shell":      shellLexer,
+	"sh":         shellLexer,
+	"bash":       shellLexer,
+	"zsh":        shellLexer,
+	"javascript": javascriptLexer,
+	"js":         javascriptLexer,
+	"c":          cLexer,
+	"cpp":        cLexer,
+	"c++":        cLexer,
+	"h":          cLexer,
+	"json":       jsonLexer,
+}
+
+func words(input string) map[string]bool {
+	result := make(map[string]bool)
+	for _, w := range strings.Fields(input) {
+		result[w] = true
+	}
+
+	return result
+}
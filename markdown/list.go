@@ -0,0 +1,204 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+)
+
+var reTask *regexp.Regexp
+
+func init() {
+	reTask = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+}
+
+// parseList consumes consecutive ordered items, or unordered items with the
+// same bullet, blank lines between items make the list loose.
+func (p *blockParser) parseList() Node {
+	first := p.peek()
+	ret := reList.FindSubmatchIndex(first.text)
+	marker := first.text[ret[4]:ret[5]]
+	ordered, start := listMarker(marker)
+
+	result := &List{
+		Position: Position{Line: first.num, Column: first.col + ret[4]},
+		Ordered:  ordered,
+		Start:    start,
+		Tight:    true,
+	}
+
+	for p.pos < len(p.lines) {
+		ret := reList.FindSubmatch(p.peek().text)
+		if ret == nil || !sameMarker(ret[2], marker) || reBreak.Match(p.peek().text) {
+			break
+		}
+
+		item, loose := p.parseListItem()
+		result.Items = append(result.Items, item)
+		if loose {
+			result.Tight = false
+		}
+
+		next := p.pos
+		for next < len(p.lines) && isBlank(p.lines[next].text) {
+			next++
+		}
+		if next == p.pos || next == len(p.lines) {
+			continue
+		}
+
+		ret = reList.FindSubmatch(p.lines[next].text)
+		if ret == nil || !sameMarker(ret[2], marker) || reBreak.Match(p.lines[next].text) {
+			break
+		}
+		p.pos = next
+		result.Tight = false
+	}
+
+	return result
+}
+
+// parseListItem consumes one item, the lines indented under the marker and
+// lazy paragraph continuations belong to it. The result reports whether
+// the direct children of the item are separated by blank lines.
+func (p *blockParser) parseListItem() (*ListItem, bool) {
+	l := p.peek()
+	p.pos++
+
+	ret := reList.FindSubmatchIndex(l.text)
+	markerIndent := indentWidth(l.text[ret[2]:ret[3]])
+	markerWidth := ret[5] - ret[4]
+	spaces := indentWidth(l.text[ret[6]:ret[7]])
+	if spaces == 0 || spaces > 4 {
+		spaces = 1
+	}
+
+	// children may be indented less than the content, as long as they are
+	// at least two columns deeper than the marker
+	contentIndent := markerIndent + markerWidth + spaces
+	indent := contentIndent
+	if markerIndent+2 < indent {
+		indent = markerIndent + 2
+	}
+
+	content := ret[6]
+	if ret[7] > ret[6] {
+		content = ret[6] + 1
+	}
+	lines := []line{{text: stripIndent(l.text[content:], spaces-1), num: l.num, col: l.col + content}}
+	if isBlank(l.text[ret[6]:]) {
+		lines[0].text = nil
+		// an item starts with at most one blank line
+		if p.pos < len(p.lines) && isBlank(p.peek().text) {
+			return &ListItem{Position: Position{Line: l.num, Column: l.col + ret[4]}}, false
+		}
+	}
+
+	var task, checked bool
+	if ret := reTask.FindSubmatchIndex(lines[0].text); ret != nil && !isBlank(lines[0].text[ret[1]:]) {
+		task, checked = true, lines[0].text[ret[2]] != ' '
+		lines[0].text = lines[0].text[ret[1]:]
+		lines[0].col += ret[1]
+	}
+
+	var para openParagraph
+	for p.pos < len(p.lines) {
+		l := p.peek()
+		if isBlank(l.text) {
+			next := p.pos
+			for next < len(p.lines) && isBlank(p.lines[next].text) {
+				next++
+			}
+			if next == len(p.lines) || indentWidth(leadingSpace(p.lines[next].text)) < indent {
+				break
+			}
+			for ; p.pos < next; p.pos++ {
+				lines = append(lines, line{num: p.peek().num, col: 1})
+			}
+			para.add(nil)
+			continue
+		}
+
+		if width := indentWidth(leadingSpace(l.text)); width >= indent {
+			if width > contentIndent {
+				width = contentIndent
+			}
+			lines = append(lines, line{text: stripIndent(l.text, width), num: l.num, col: l.col + width})
+			para.add(lines[len(lines)-1].text)
+			p.pos++
+			continue
+		}
+
+		if p.interrupts(p.pos) || reList.Match(l.text) || !para.accepts(p, lines) {
+			break
+		}
+		l.lazy = true
+		lines = append(lines, l)
+		p.pos++
+	}
+
+	item := &ListItem{
+		Position: Position{Line: l.num, Column: l.col + ret[4]},
+		Task:     task,
+		Checked:  checked,
+		Blocks:   p.ext.parseBlocks(lines),
+	}
+
+	blank := make(map[int]bool)
+	for _, l := range lines {
+		if isBlank(l.text) {
+			blank[l.num] = true
+		}
+	}
+
+	loose := false
+	for i := 1; i < len(item.Blocks); i++ {
+		if blank[item.Blocks[i].Pos().Line-1] {
+			loose = true
+		}
+	}
+
+	return item, loose
+}
+
+// listMarker reports whether marker is an ordered list marker, and the
+// start number for it.
+func listMarker(marker []byte) (bool, int) {
+	if marker[0] < '0' || marker[0] > '9' {
+		return false, 0
+	}
+
+	start, err := strconv.Atoi(string(marker[:len(marker)-1]))
+	if err != nil {
+		return true, 1
+	}
+
+	return true, start
+}
+
+// sameMarker reports whether two list markers belong to the same list, the
+// items of an unordered list have the same bullet, and ordered items may
+// mix `1.` and `1)`.
+func sameMarker(a, b []byte) bool {
+	orderedA, _ := listMarker(a)
+	orderedB, _ := listMarker(b)
+	if orderedA || orderedB {
+		return orderedA == orderedB
+	}
+
+	return a[0] == b[0]
+}
+
+// indentWidth counts the leading whitespace width, a tab stands for four
+// spaces.
+func indentWidth(input []byte) int {
+	width := 0
+	for _, c := range input {
+		if c == '\t' {
+			width += 4 - width%4
+		} else {
+			width++
+		}
+	}
+
+	return width
+}
@@ -4,8 +4,11 @@ import (
 	"testing"
 
 	"bytes"
+	"errors"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 )
 
 func TestParseHeader(t *testing.T) {
@@ -16,13 +19,13 @@ func TestParseHeader(t *testing.T) {
 	}
 
 	output := [][]byte{
-		[]byte("\n<h4> test! </h4>\n"),
-		[]byte("\n<h3> 中文 </h3>\n"),
-		[]byte("invalid"),
+		[]byte("\n<h4 id=\"test\"> test! </h4>\n"),
+		[]byte("\n<h3 id=\"中文\"> 中文 </h3>\n"),
+		[]byte("\n<p>invalid</p>\n"),
 	}
 
 	for i, v := range input {
-		result := parseHeader(v)
+		result := Render(v)
 		if !bytes.Equal(result, output[i]) {
 			t.Fatalf("ParseHeader fail, [%s] vs [%s]", string(result), string(output[i]))
 		}
@@ -37,11 +40,11 @@ func TestParseImage(t *testing.T) {
 
 	output := [][]byte{
 		[]byte("\n<img src=\"http://www.hackcv.com/test.jpg\" alt=\"xxx\">\n"),
-		[]byte("xxx.jpg"),
+		[]byte("\n<p>xxx.jpg</p>\n"),
 	}
 
 	for i, v := range input {
-		result := parseImage(v)
+		result := Render(v)
 		if !bytes.Equal(result, output[i]) {
 			t.Fatalf("ParseImage fail, [%s] vs [%s]", string(result), string(output[i]))
 		}
@@ -58,16 +61,60 @@ func TestParseList(t *testing.T) {
 	output := [][]byte{
 		[]byte("\n<ul>\n<li>block quote</li>\n</ul>\n"),
 		[]byte("\n<ul>\n<li>valid</li>\n</ul>\n"),
-		[]byte("invalid"),
+		[]byte("\n<p>invalid</p>\n"),
 	}
 	for i, v := range input {
-		result := parseList(v)
+		result := Render(v)
 		if !bytes.Equal(result, output[i]) {
 			t.Fatalf("ParseQuote fail, [%s] vs [%s]", string(result), string(output[i]))
 		}
 	}
 }
 
+func TestParseOrderedList(t *testing.T) {
+	input := [][]byte{
+		[]byte("1. first\n2. second"),
+		[]byte("3. third\n4) fourth"),
+		[]byte("1. *first*\n* second"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n"),
+		[]byte("\n<ol start=\"3\">\n<li>third</li>\n<li>fourth</li>\n</ol>\n"),
+		[]byte("\n<ol>\n<li><em>first</em></li>\n</ol>\n\n<ul>\n<li>second</li>\n</ul>\n"),
+	}
+	for i, v := range input {
+		result := Render(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("ParseOrderedList fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
+func TestParseNestedList(t *testing.T) {
+	input := [][]byte{
+		[]byte("- a\n  - b\n    - c\n- d"),
+		[]byte("1. step one\n   - detail\n   - more\n2. step two"),
+		[]byte("- a\n\t1. b\n\t2. c"),
+		[]byte("1. step one which\n   continues here\n2. step two"),
+		[]byte("- a\n  - b\n      continued\n  - c"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<ul>\n<li>a\n<ul>\n<li>b\n<ul>\n<li>c</li>\n</ul>\n</li>\n</ul>\n</li>\n<li>d</li>\n</ul>\n"),
+		[]byte("\n<ol>\n<li>step one\n<ul>\n<li>detail</li>\n<li>more</li>\n</ul>\n</li>\n<li>step two</li>\n</ol>\n"),
+		[]byte("\n<ul>\n<li>a\n<ol>\n<li>b</li>\n<li>c</li>\n</ol>\n</li>\n</ul>\n"),
+		[]byte("\n<ol>\n<li>step one which\ncontinues here</li>\n<li>step two</li>\n</ol>\n"),
+		[]byte("\n<ul>\n<li>a\n<ul>\n<li>b\ncontinued</li>\n<li>c</li>\n</ul>\n</li>\n</ul>\n"),
+	}
+	for i, v := range input {
+		result := Render(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("ParseNestedList fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
 func TestParseQuote(t *testing.T) {
 	input := [][]byte{
 		[]byte("> block quote"),
@@ -75,34 +122,147 @@ func TestParseQuote(t *testing.T) {
 	}
 
 	output := [][]byte{
-		[]byte("\n<blockquote>block quote</blockquote>\n"),
-		[]byte("invalid"),
+		[]byte("\n<blockquote>\n<p>block quote</p>\n</blockquote>\n"),
+		[]byte("\n<p>invalid</p>\n"),
 	}
 	for i, v := range input {
-		result := parseQuote(v)
+		result := Render(v)
 		if !bytes.Equal(result, output[i]) {
 			t.Fatalf("ParseQuote fail, [%s] vs [%s]", string(result), string(output[i]))
 		}
 	}
 }
 
+func TestParseTable(t *testing.T) {
+	input := [][]byte{
+		[]byte("| a | b |\n| --- | --- |\n| 1 | 2 |"),
+		[]byte("left | center | right\n:--- | :---: | ---:\nx | y | z"),
+		[]byte("| code | note |\n|---|---|\n| `a \\| b` | **bold** |\n| only |"),
+		[]byte("| a |\n|---|"),
+		[]byte("a | b\n--- | --- | ---"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n"),
+		[]byte("\n<table>\n<thead>\n<tr>\n<th align=\"left\">left</th>\n<th align=\"center\">center</th>\n<th align=\"right\">right</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td align=\"left\">x</td>\n<td align=\"center\">y</td>\n<td align=\"right\">z</td>\n</tr>\n</tbody>\n</table>\n"),
+		[]byte("\n<table>\n<thead>\n<tr>\n<th>code</th>\n<th>note</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td><code>a | b</code></td>\n<td><strong>bold</strong></td>\n</tr>\n<tr>\n<td>only</td>\n<td></td>\n</tr>\n</tbody>\n</table>\n"),
+		[]byte("\n<table>\n<thead>\n<tr>\n<th>a</th>\n</tr>\n</thead>\n</table>\n"),
+		[]byte("\n<p>a | b\n--- | --- | ---</p>\n"),
+	}
+	for i, v := range input {
+		result := Render(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("ParseTable fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
+func TestParseThematicBreak(t *testing.T) {
+	input := [][]byte{
+		[]byte("one\n\n---\n\ntwo"),
+		[]byte("* * *"),
+		[]byte("- one\n___\n- two"),
+		[]byte("--"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<p>one</p>\n\n<hr>\n\n<p>two</p>\n"),
+		[]byte("\n<hr>\n"),
+		[]byte("\n<ul>\n<li>one</li>\n</ul>\n\n<hr>\n\n<ul>\n<li>two</li>\n</ul>\n"),
+		[]byte("\n<p>--</p>\n"),
+	}
+	for i, v := range input {
+		result := Render(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("ParseThematicBreak fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
+func TestParseSetextHeader(t *testing.T) {
+	input := [][]byte{
+		[]byte("Title\n====="),
+		[]byte("Sub *title*\n---\ntext"),
+		[]byte("> quote\n==="),
+	}
+
+	output := [][]byte{
+		[]byte("\n<h1 id=\"title\"> Title </h1>\n"),
+		[]byte("\n<h2 id=\"sub-title\"> Sub <em>title</em> </h2>\n\n<p>text</p>\n"),
+		[]byte("\n<blockquote>\n<p>quote\n===</p>\n</blockquote>\n"),
+	}
+	for i, v := range input {
+		result := Render(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("ParseSetextHeader fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
+func TestParseTaskList(t *testing.T) {
+	input := [][]byte{
+		[]byte("- [ ] todo\n- [x] done\n- [] not a task"),
+		[]byte("1. [X] first\n\n   more"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<ul>\n<li class=\"task-list-item\"><input type=\"checkbox\" disabled> todo</li>\n" +
+			"<li class=\"task-list-item\"><input type=\"checkbox\" checked disabled> done</li>\n<li>[] not a task</li>\n</ul>\n"),
+		[]byte("\n<ol>\n<li class=\"task-list-item\">\n<p><input type=\"checkbox\" checked disabled> first</p>\n\n<p>more</p>\n</li>\n</ol>\n"),
+	}
+	for i, v := range input {
+		result := Render(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("ParseTaskList fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
+func TestParseHardBreak(t *testing.T) {
+	input := [][]byte{
+		[]byte("one  \ntwo"),
+		[]byte("one\\\ntwo"),
+		[]byte("one \ntwo"),
+		[]byte("`one  \ntwo`"),
+		[]byte("trailing  "),
+	}
+
+	output := [][]byte{
+		[]byte("one<br>\ntwo"),
+		[]byte("one<br>\ntwo"),
+		[]byte("one\ntwo"),
+		[]byte("<code>one   two</code>"),
+		[]byte("trailing"),
+	}
+	for i, v := range input {
+		result := renderText(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("ParseHardBreak fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
 func TestParseInlineEmphasis(t *testing.T) {
 	input := [][]byte{
 		[]byte("just **test** **test** test"),
 		[]byte("just __test__ __test__ test"),
 		[]byte("****normal test"),
 		[]byte("normal test"),
+		[]byte("2 * 3 is **six**"),
+		[]byte("***both*** and **nested *em***"),
 	}
 
 	output := [][]byte{
 		[]byte("just <strong>test</strong> <strong>test</strong> test"),
 		[]byte("just <strong>test</strong> <strong>test</strong> test"),
+		[]byte("****normal test"),
 		[]byte("normal test"),
-		[]byte("normal test"),
+		[]byte("2 * 3 is <strong>six</strong>"),
+		[]byte("<em><strong>both</strong></em> and <strong>nested <em>em</em></strong>"),
 	}
 
 	for i, v := range input {
-		r := parseInlineEmphasis(v)
+		r := renderText(v)
 		if !bytes.Equal(r, output[i]) {
 			t.Fatalf("ParseInlineEmphasis fail, [%s] vs [%s]", string(r), string(output[i]))
 		}
@@ -115,17 +275,21 @@ func TestParseInlineItalics(t *testing.T) {
 		[]byte("just _test_ _test_ test"),
 		[]byte("**normal test"),
 		[]byte("normal test"),
+		[]byte("snake_case_identifier and _this_"),
+		[]byte("\\*not\\* *a \\* b*"),
 	}
 
 	output := [][]byte{
 		[]byte("just <em>test</em> <em>test</em> test"),
 		[]byte("just <em>test</em> <em>test</em> test"),
+		[]byte("**normal test"),
 		[]byte("normal test"),
-		[]byte("normal test"),
+		[]byte("snake_case_identifier and <em>this</em>"),
+		[]byte("*not* <em>a * b</em>"),
 	}
 
 	for i, v := range input {
-		r := parseInlineItalics(v)
+		r := renderText(v)
 		if !bytes.Equal(r, output[i]) {
 			t.Fatalf("ParseInlineItalics fail, [%s] vs [%s]", string(r), string(output[i]))
 		}
@@ -135,18 +299,20 @@ func TestParseInlineItalics(t *testing.T) {
 func TestParseInlineStrike(t *testing.T) {
 	input := [][]byte{
 		[]byte("just ~~test~~ ~~test~~ test"),
-		[]byte("~~~~normal test"),
+		[]byte("just ~~~~normal test"),
 		[]byte("normal test"),
+		[]byte("~~one~~ ~~two"),
 	}
 
 	output := [][]byte{
 		[]byte("just <del>test</del> <del>test</del> test"),
+		[]byte("just ~~~~normal test"),
 		[]byte("normal test"),
-		[]byte("normal test"),
+		[]byte("<del>one</del> ~~two"),
 	}
 
 	for i, v := range input {
-		r := parseInlineStrike(v)
+		r := renderText(v)
 		if !bytes.Equal(r, output[i]) {
 			t.Fatalf("ParseInlineStrike fail, [%s] vs [%s]", string(r), string(output[i]))
 		}
@@ -156,18 +322,22 @@ func TestParseInlineStrike(t *testing.T) {
 func TestParseInlineCode(t *testing.T) {
 	input := [][]byte{
 		[]byte("just `test` `test` test"),
-		[]byte("````normal test"),
+		[]byte("just ````normal test"),
 		[]byte("normal test"),
+		[]byte("`a_b_c` and `` `*x*` ``"),
+		[]byte("`open *em*"),
 	}
 
 	output := [][]byte{
 		[]byte("just <code>test</code> <code>test</code> test"),
+		[]byte("just ````normal test"),
 		[]byte("normal test"),
-		[]byte("normal test"),
+		[]byte("<code>a_b_c</code> and <code>`*x*`</code>"),
+		[]byte("`open <em>em</em>"),
 	}
 
 	for i, v := range input {
-		r := parseInlineCode(v)
+		r := renderText(v)
 		if !bytes.Equal(r, output[i]) {
 			t.Fatalf("ParseInlineCode fail, [%s] vs [%s]", string(r), string(output[i]))
 		}
@@ -185,7 +355,7 @@ func TestParseCode(t *testing.T) {
 		[]byte("\n<pre>\n<code>\nvalid\n</code>\n</pre>\n"),
 	}
 	for i, v := range input {
-		result := parseCode(v)
+		result := Render(v)
 		if !bytes.Equal(result, output[i]) {
 			t.Fatalf("ParseCode fail, [%s] vs [%s]", string(result), string(output[i]))
 		}
@@ -196,14 +366,30 @@ func TestParseInlineLink(t *testing.T) {
 	input := [][]byte{
 		[]byte("test [link](http://hackcv.com)"),
 		[]byte("[test](http://baidu.com) test [link](http://hackcv.com)"),
+		[]byte("[link](http://hackcv.com) and the rest"),
+		[]byte("[link](http://hackcv.com/a_b_c \"the title\")"),
+		[]byte("[link](</my page>) [empty]()"),
+		[]byte("<http://hackcv.com/?a=1&b=2> <mail@hackcv.com>"),
+		[]byte("see http://hackcv.com/a_b. and www.hackcv.com, (https://go.dev/doc)"),
+		[]byte("[http://hackcv.com](http://hackcv.com)"),
+		[]byte("![logo](/logo.png 'Logo') [not a link]"),
+		[]byte("`[code](http://hackcv.com)`"),
 	}
 
 	output := [][]byte{
 		[]byte("test <a href=\"http://hackcv.com\">link</a>"),
 		[]byte("<a href=\"http://baidu.com\">test</a> test <a href=\"http://hackcv.com\">link</a>"),
+		[]byte("<a href=\"http://hackcv.com\">link</a> and the rest"),
+		[]byte("<a href=\"http://hackcv.com/a_b_c\" title=\"the title\">link</a>"),
+		[]byte("<a href=\"/my%20page\">link</a> <a href=\"\">empty</a>"),
+		[]byte("<a href=\"http://hackcv.com/?a=1&amp;b=2\">http://hackcv.com/?a=1&amp;b=2</a> <a href=\"mailto:mail@hackcv.com\">mail@hackcv.com</a>"),
+		[]byte("see <a href=\"http://hackcv.com/a_b\">http://hackcv.com/a_b</a>. and <a href=\"http://www.hackcv.com\">www.hackcv.com</a>, (<a href=\"https://go.dev/doc\">https://go.dev/doc</a>)"),
+		[]byte("<a href=\"http://hackcv.com\">http://hackcv.com</a>"),
+		[]byte("<img src=\"/logo.png\" alt=\"logo\" title=\"Logo\"> [not a link]"),
+		[]byte("<code>[code](http://hackcv.com)</code>"),
 	}
 	for i, v := range input {
-		result := parseInlineLink(v)
+		result := renderText(v)
 		if !bytes.Equal(result, output[i]) {
 			t.Fatalf("ParseCode fail, [%s] vs [%s]", string(result), string(output[i]))
 		}
@@ -212,8 +398,7 @@ func TestParseInlineLink(t *testing.T) {
 
 func TestBlock(t *testing.T) {
 	input := "```shell\n./configure\nmake\nmake install\n```"
-	block := NewBlock([]byte(input))
-	result := block.Render()
+	result := Render([]byte(input))
 	t.Log(string(result))
 }
 
@@ -232,3 +417,85 @@ func TestRender(t *testing.T) {
 	result := Render(b)
 	t.Log(string(result))
 }
+
+// renderText renders input as a single paragraph and strips the paragraph.
+func renderText(input []byte) []byte {
+	result := bytes.TrimPrefix(Render(input), []byte("\n<p>"))
+	return bytes.TrimSuffix(result, []byte("</p>\n"))
+}
+
+// samplePosts loads the corpus of testdata/posts.
+func samplePosts(tb testing.TB) [][]byte {
+	files, err := filepath.Glob("testdata/posts/*.md")
+	if err != nil || len(files) == 0 {
+		tb.Fatalf("sample posts fail, %v", err)
+	}
+
+	posts := make([][]byte, len(files))
+	for i, file := range files {
+		if posts[i], err = ioutil.ReadFile(file); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	return posts
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRenderTo(t *testing.T) {
+	opts := Options{Highlight: &HighlightOptions{}, HeadingAnchors: true}
+	for _, post := range samplePosts(t) {
+		var buffer bytes.Buffer
+		if err := RenderTo(&buffer, bytes.NewReader(post), opts); err != nil {
+			t.Fatal(err)
+		}
+		if expect := RenderWithOptions(post, opts); !bytes.Equal(buffer.Bytes(), expect) {
+			t.Fatalf("RenderTo fail, [%s] vs [%s]", buffer.String(), string(expect))
+		}
+	}
+
+	if err := RenderTo(failWriter{}, bytes.NewReader([]byte("text")), Options{}); err == nil || err.Error() != "disk full" {
+		t.Fatalf("RenderTo fail, %v", err)
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	posts := samplePosts(b)
+	size := 0
+	for _, post := range posts {
+		size += len(post)
+	}
+
+	b.SetBytes(int64(size))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, post := range posts {
+			Render(post)
+		}
+	}
+}
+
+func BenchmarkRenderTo(b *testing.B) {
+	posts := samplePosts(b)
+	size := 0
+	for _, post := range posts {
+		size += len(post)
+	}
+
+	b.SetBytes(int64(size))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, post := range posts {
+			if err := RenderTo(io.Discard, bytes.NewReader(post), Options{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+)
+
+var reMathBlock *regexp.Regexp
+
+func init() {
+	reMathBlock = regexp.MustCompile(`^ {0,3}\$\$`)
+}
+
+// MathMode decides how math is rendered.
+type MathMode int
+
+const (
+	// MathKaTeX wraps the TeX source into the markup of the KaTeX
+	// auto-render extension, it is typeset in the browser. It is the
+	// default mode.
+	MathKaTeX MathMode = iota
+	// MathMathML converts the TeX source to MathML at build time, math
+	// which uses unsupported commands falls back to the KaTeX markup.
+	MathMathML
+)
+
+// isMathBlock reports whether the line at index i opens a `$$` display
+// block, which is closed by a line ending with `$$` before a blank line.
+func (p *blockParser) isMathBlock(i int) bool {
+	_, ok := p.mathBlockEnd(i)
+	return ok
+}
+
+// mathBlockEnd is the index of the line which closes the display block
+// opened at index i.
+func (p *blockParser) mathBlockEnd(i int) (int, bool) {
+	text := p.lines[i].text
+	if !reMathBlock.Match(text) {
+		return 0, false
+	}
+
+	// `$$ x $$` on a single line
+	rest := bytes.TrimSpace(text)[2:]
+	if len(rest) > 2 && bytes.HasSuffix(rest, []byte("$$")) {
+		return i, true
+	}
+	if len(bytes.TrimSpace(rest)) > 0 && bytes.Contains(rest, []byte("$$")) {
+		return 0, false
+	}
+
+	for j := i + 1; j < len(p.lines) && !isBlank(p.lines[j].text); j++ {
+		if bytes.HasSuffix(bytes.TrimSpace(p.lines[j].text), []byte("$$")) {
+			return j, true
+		}
+	}
+
+	return 0, false
+}
+
+func (p *blockParser) parseMathBlock() Node {
+	first := p.peek()
+	end, _ := p.mathBlockEnd(p.pos)
+
+	var texts [][]byte
+	for ; p.pos <= end; p.pos++ {
+		texts = append(texts, bytes.TrimSpace(p.peek().text))
+	}
+	tex := bytes.Join(texts, lineTrail)
+	tex = bytes.TrimSuffix(bytes.TrimPrefix(tex, []byte("$$")), []byte("$$"))
+
+	return &MathBlock{
+		Position: Position{Line: first.num, Column: first.col + len(leadingSpace(first.text))},
+		TeX:      bytes.TrimSpace(tex),
+	}
+}
+
+// parseMath matches `$...$` and `$$...$$`, the content is not parsed for
+// emphasis. The opening `$` must be followed by a non-space character, and
+// the closing one preceded by a non-space character and not followed by a
+// digit, so amounts such as $5 stay text.
+func (p *inlineParser) parseMath(i int) int {
+	n := runLength(p.input, i)
+	if n > 2 {
+		p.appendText(i, p.input[i:i+n]...)
+		return i + n
+	}
+
+	start := i + n
+	if start >= len(p.input) || isSpace(p.input[start]) {
+		return i
+	}
+
+	for j := start; j < len(p.input); j++ {
+		switch p.input[j] {
+		case '\\':
+			j++
+
+		case '$':
+			m := runLength(p.input, j)
+			if m != n || isSpace(p.input[j-1]) || j == start {
+				j += m - 1
+				continue
+			}
+			if n == 1 && j+1 < len(p.input) && '0' <= p.input[j+1] && p.input[j+1] <= '9' {
+				continue
+			}
+
+			p.flushText()
+			p.append(&Math{Position: p.position(i), Display: n == 2, TeX: p.input[start:j]})
+			return j + n
+		}
+	}
+
+	return i
+}
+
+// renderMath writes tex as MathML if it is enabled and tex is supported,
+// and as KaTeX markup otherwise.
+func (r *htmlRenderer) renderMath(tex []byte, display bool) {
+	if r.opts.Math == MathMathML {
+		if result, err := texToMathML(string(tex), display); err == nil {
+			r.buffer.Write(result)
+			return
+		}
+	}
+
+	if display {
+		r.buffer.WriteString("<span class=\"math display\">\\[")
+		r.writeEscaped(tex)
+		r.buffer.WriteString("\\]</span>")
+		return
+	}
+	r.buffer.WriteString("<span class=\"math inline\">\\(")
+	r.writeEscaped(tex)
+	r.buffer.WriteString("\\)</span>")
+}
+
+// HasMath reports whether the tree rooted at node contains math, pages
+// without it need not load KaTeX.
+func HasMath(node Node) bool {
+	found := false
+	Walk(node, func(n Node) bool {
+		switch n.(type) {
+		case *Math, *MathBlock:
+			found = true
+		}
+		return !found
+	})
+
+	return found
+}
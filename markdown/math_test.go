@@ -0,0 +1,76 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMath(t *testing.T) {
+	input := [][]byte{
+		[]byte("where $a_i * b_j$ and *em*"),
+		[]byte("costs $5 and $10, or $ x $"),
+		[]byte("$$\n\\sum_{i=1}^{n} x_i < y\n$$"),
+		[]byte("$$ E = mc^2 $$"),
+		[]byte("inline $$E$$ and \\$x$"),
+		[]byte("`$x$` and $a`b$"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<p>where <span class=\"math inline\">\\(a_i * b_j\\)</span> and <em>em</em></p>\n"),
+		[]byte("\n<p>costs $5 and $10, or $ x $</p>\n"),
+		[]byte("\n<p><span class=\"math display\">\\[\\sum_{i=1}^{n} x_i &lt; y\\]</span></p>\n"),
+		[]byte("\n<p><span class=\"math display\">\\[E = mc^2\\]</span></p>\n"),
+		[]byte("\n<p>inline <span class=\"math display\">\\[E\\]</span> and $x$</p>\n"),
+		[]byte("\n<p><code>$x$</code> and <span class=\"math inline\">\\(a`b\\)</span></p>\n"),
+	}
+
+	for i, v := range input {
+		result := Render(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("Math fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
+func TestMathML(t *testing.T) {
+	input := []string{
+		`a_i^2`,
+		`\frac{\alpha}{\sqrt{x}} \le \Gamma`,
+		`\sum_{i=1}^n x'`,
+		`\left( \mathbb{R} \right) \text{if } x`,
+		`\begin{pmatrix} a & b \\ c & d \end{pmatrix}`,
+	}
+
+	output := []string{
+		`<msubsup><mi>a</mi><mi>i</mi><mn>2</mn></msubsup>`,
+		`<mfrac><mrow><mi>α</mi></mrow><mrow><msqrt><mrow><mi>x</mi></mrow></msqrt></mrow></mfrac><mo>≤</mo><mi mathvariant="normal">Γ</mi>`,
+		`<munderover><mo movablelimits="true">∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover><msup><mi>x</mi><mo>′</mo></msup>`,
+		`<mrow><mo fence="true" stretchy="true">(</mo><mrow><mi mathvariant="double-struck">R</mi></mrow><mo fence="true" stretchy="true">)</mo></mrow><mtext>if </mtext><mi>x</mi>`,
+		`<mrow><mo fence="true">(</mo><mtable><mtr><mtd><mi>a</mi></mtd><mtd><mi>b</mi></mtd></mtr><mtr><mtd><mi>c</mi></mtd><mtd><mi>d</mi></mtd></mtr></mtable><mo fence="true">)</mo></mrow>`,
+	}
+
+	for i, v := range input {
+		result, err := texToMathML(v, true)
+		if err != nil {
+			t.Fatalf("MathML fail, %s: %v", v, err)
+		}
+		expect := `<math xmlns="http://www.w3.org/1998/Math/MathML" display="block"><semantics><mrow>` + output[i] +
+			`</mrow><annotation encoding="application/x-tex">` + string(escapeHTML([]byte(v))) + `</annotation></semantics></math>`
+		if string(result) != expect {
+			t.Fatalf("MathML fail, [%s] vs [%s]", string(result), expect)
+		}
+	}
+}
+
+func TestMathMLFallback(t *testing.T) {
+	input := []byte("$\\unknown{x}$ and $x_1$")
+	output := "\n<p><span class=\"math inline\">\\(\\unknown{x}\\)</span> and <math xmlns=\"http://www.w3.org/1998/Math/MathML\"><semantics><mrow><msub><mi>x</mi><mn>1</mn></msub></mrow><annotation encoding=\"application/x-tex\">x_1</annotation></semantics></math></p>\n"
+
+	result := RenderWithOptions(input, Options{Math: MathMathML})
+	if string(result) != output {
+		t.Fatalf("MathMLFallback fail, [%s] vs [%s]", string(result), output)
+	}
+	if !HasMath(Parse(input)) || HasMath(Parse([]byte("costs $5"))) {
+		t.Fatalf("HasMath fail")
+	}
+}
@@ -0,0 +1,710 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type texKind int
+
+const (
+	texEOF texKind = iota
+	texCommand
+	texLetter
+	texNumber
+	texOperator
+	texText
+	texOpen
+	texClose
+	texSup
+	texSub
+	texAlign
+)
+
+type texToken struct {
+	kind texKind
+	text string
+}
+
+var (
+	texGreek = map[string]string{
+		"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ",
+		"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ",
+		"iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+		"pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ", "sigma": "σ",
+		"varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ", "varphi": "φ",
+		"chi": "χ", "psi": "ψ", "omega": "ω",
+		"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+		"Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ",
+		"Omega": "Ω",
+	}
+
+	texIdentifiers = map[string]string{
+		"infty": "∞", "partial": "∂", "nabla": "∇", "emptyset": "∅",
+		"varnothing": "∅", "ell": "ℓ", "hbar": "ℏ", "Re": "ℜ", "Im": "ℑ",
+		"aleph": "ℵ", "angle": "∠", "top": "⊤", "bot": "⊥",
+	}
+
+	texOperators = map[string]string{
+		"pm": "±", "mp": "∓", "times": "×", "div": "÷", "cdot": "⋅", "ast": "∗",
+		"star": "⋆", "circ": "∘", "bullet": "∙", "le": "≤", "leq": "≤",
+		"ge": "≥", "geq": "≥", "neq": "≠", "ne": "≠", "approx": "≈", "sim": "∼",
+		"simeq": "≃", "equiv": "≡", "cong": "≅", "propto": "∝", "ll": "≪",
+		"gg": "≫", "in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂",
+		"subseteq": "⊆", "supset": "⊃", "supseteq": "⊇", "cup": "∪",
+		"cap": "∩", "setminus": "∖", "wedge": "∧", "land": "∧", "vee": "∨",
+		"lor": "∨", "neg": "¬", "lnot": "¬", "forall": "∀", "exists": "∃",
+		"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←",
+		"leftrightarrow": "↔", "Rightarrow": "⇒", "Leftarrow": "⇐",
+		"Leftrightarrow": "⇔", "implies": "⟹", "iff": "⟺", "mapsto": "↦",
+		"uparrow": "↑", "downarrow": "↓", "mid": "∣", "parallel": "∥",
+		"perp": "⊥", "langle": "⟨", "rangle": "⟩", "lfloor": "⌊",
+		"rfloor": "⌋", "lceil": "⌈", "rceil": "⌉", "ldots": "…", "cdots": "⋯",
+		"vdots": "⋮", "ddots": "⋱", "dots": "…", "colon": ":", "oplus": "⊕",
+		"otimes": "⊗", "odot": "⊙", "vert": "|", "Vert": "‖", "prime": "′",
+		"{": "{", "}": "}", "|": "‖", "bmod": "mod",
+	}
+
+	// texBigOperators take their scripts as limits in display math
+	texBigOperators = map[string]string{
+		"sum": "∑", "prod": "∏", "coprod": "∐", "bigcup": "⋃", "bigcap": "⋂",
+		"bigoplus": "⨁", "bigotimes": "⨂", "bigvee": "⋁", "bigwedge": "⋀",
+	}
+
+	texIntegrals = map[string]string{
+		"int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+	}
+
+	texFunctions = words(`sin cos tan cot sec csc arcsin arccos arctan sinh cosh
+		tanh coth log ln lg exp det dim ker deg gcd hom arg Pr`)
+
+	texLimitFunctions = words(`lim liminf limsup max min sup inf`)
+
+	texFonts = map[string]string{
+		"mathrm": "normal", "mathbf": "bold", "mathit": "italic",
+		"mathbb": "double-struck", "mathcal": "script", "mathsf": "sans-serif",
+		"mathtt": "monospace", "mathfrak": "fraktur", "boldsymbol": "bold-italic",
+	}
+
+	texAccents = map[string]string{
+		"hat": "^", "widehat": "^", "bar": "¯", "overline": "‾", "vec": "→",
+		"dot": "˙", "ddot": "¨", "tilde": "~", "widetilde": "~", "check": "ˇ",
+		"breve": "˘", "acute": "´", "grave": "`",
+	}
+
+	texSpaces = map[string]string{
+		",": "0.1667em", ":": "0.2222em", ">": "0.2222em", ";": "0.2778em",
+		"!": "-0.1667em", "quad": "1em", "qquad": "2em",
+	}
+
+	// texTexts take their argument as text, spaces included
+	texTexts = map[string]string{
+		"text": "", "textrm": "", "mbox": "", "textbf": "bold",
+		"textit": "italic", "operatorname": "",
+	}
+
+	texSizes = words(`big Big bigg Bigg bigl Bigl biggl Biggl bigr Bigr biggr
+		Biggr bigm Bigm`)
+
+	// texFences are the delimiters around the matrix environments
+	texFences = map[string][2]string{
+		"matrix":   {"", ""},
+		"pmatrix":  {"(", ")"},
+		"bmatrix":  {"[", "]"},
+		"Bmatrix":  {"{", "}"},
+		"vmatrix":  {"|", "|"},
+		"Vmatrix":  {"‖", "‖"},
+		"cases":    {"{", ""},
+		"aligned":  {"", ""},
+		"align":    {"", ""},
+		"align*":   {"", ""},
+		"gathered": {"", ""},
+	}
+)
+
+// tokenizeTeX splits tex into tokens, the whitespace between them is
+// dropped.
+func tokenizeTeX(tex string) []texToken {
+	var tokens []texToken
+	for i := 0; i < len(tex); {
+		c := tex[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '\\' && i+1 < len(tex) && isLetter(tex[i+1]):
+			j := i + 1
+			for j < len(tex) && isLetter(tex[j]) {
+				j++
+			}
+			name := tex[i+1 : j]
+			tokens = append(tokens, texToken{texCommand, name})
+			i = j
+
+			if _, ok := texTexts[name]; ok {
+				for i < len(tex) && tex[i] == ' ' {
+					i++
+				}
+				if end := texGroupEnd(tex, i); end > i {
+					tokens = append(tokens, texToken{texText, tex[i+1 : end]})
+					i = end + 1
+				}
+			}
+
+		case c == '\\' && i+1 < len(tex):
+			_, n := utf8.DecodeRuneInString(tex[i+1:])
+			tokens = append(tokens, texToken{texCommand, tex[i+1 : i+1+n]})
+			i += 1 + n
+
+		case c == '{':
+			tokens = append(tokens, texToken{texOpen, "{"})
+			i++
+
+		case c == '}':
+			tokens = append(tokens, texToken{texClose, "}"})
+			i++
+
+		case c == '^':
+			tokens = append(tokens, texToken{texSup, "^"})
+			i++
+
+		case c == '_':
+			tokens = append(tokens, texToken{texSub, "_"})
+			i++
+
+		case c == '&':
+			tokens = append(tokens, texToken{texAlign, "&"})
+			i++
+
+		case '0' <= c && c <= '9':
+			j := i
+			for j < len(tex) && ('0' <= tex[j] && tex[j] <= '9' || tex[j] == '.' && j+1 < len(tex) && '0' <= tex[j+1] && tex[j+1] <= '9') {
+				j++
+			}
+			tokens = append(tokens, texToken{texNumber, tex[i:j]})
+			i = j
+
+		default:
+			r, n := utf8.DecodeRuneInString(tex[i:])
+			kind := texOperator
+			if unicode.IsLetter(r) {
+				kind = texLetter
+			}
+			tokens = append(tokens, texToken{kind, tex[i : i+n]})
+			i += n
+		}
+	}
+
+	return tokens
+}
+
+// texGroupEnd is the index of the brace closing the group opened at
+// tex[i], or i if there is none.
+func texGroupEnd(tex string, i int) int {
+	if i >= len(tex) || tex[i] != '{' {
+		return i
+	}
+
+	depth := 0
+	for j := i; j < len(tex); j++ {
+		switch tex[j] {
+		case '\\':
+			j++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+
+	return i
+}
+
+// mathParser builds presentation MathML from the tokens of a formula.
+type mathParser struct {
+	tokens  []texToken
+	pos     int
+	display bool
+	// variant is the mathvariant of the identifiers inside of a font
+	// command
+	variant string
+}
+
+// texToMathML converts a subset of LaTeX math to MathML, the TeX source is
+// kept as an annotation. It fails on the commands it does not know.
+func texToMathML(tex string, display bool) ([]byte, error) {
+	p := &mathParser{tokens: tokenizeTeX(tex), display: display}
+	row, err := p.parseRow(func(texToken) bool { return false })
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s", p.peek().text)
+	}
+
+	var builder strings.Builder
+	builder.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML"`)
+	if display {
+		builder.WriteString(` display="block"`)
+	}
+	builder.WriteString("><semantics><mrow>")
+	builder.WriteString(row)
+	builder.WriteString(`</mrow><annotation encoding="application/x-tex">`)
+	builder.Write(escapeHTML([]byte(tex)))
+	builder.WriteString("</annotation></semantics></math>")
+
+	return []byte(builder.String()), nil
+}
+
+func (p *mathParser) peek() texToken {
+	if p.pos >= len(p.tokens) {
+		return texToken{kind: texEOF}
+	}
+
+	return p.tokens[p.pos]
+}
+
+func (p *mathParser) next() texToken {
+	tok := p.peek()
+	if tok.kind != texEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+// parseRow parses elements up to the end of the formula or the token stop
+// reports true for, which is not consumed.
+func (p *mathParser) parseRow(stop func(texToken) bool) (string, error) {
+	var builder strings.Builder
+	for {
+		tok := p.peek()
+		if tok.kind == texEOF || tok.kind == texClose || stop(tok) {
+			return builder.String(), nil
+		}
+
+		elem, err := p.parseScripts()
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(elem)
+	}
+}
+
+// parseScripts parses an element with its subscript and superscript, the
+// scripts of big operators are limits in display math.
+func (p *mathParser) parseScripts() (string, error) {
+	base, limits, err := p.parseAtom()
+	if err != nil {
+		return "", err
+	}
+
+	var sub string
+	var sup []string
+	var hasSup bool
+	for {
+		tok := p.peek()
+		switch {
+		case tok.kind == texSub && sub == "":
+			p.next()
+			if sub, err = p.parseArgument(); err != nil {
+				return "", err
+			}
+
+		case tok.kind == texSup && !hasSup:
+			p.next()
+			hasSup = true
+			script, err := p.parseArgument()
+			if err != nil {
+				return "", err
+			}
+			sup = append(sup, script)
+
+		case tok.kind == texOperator && tok.text == "'":
+			p.next()
+			sup = append(sup, "<mo>′</mo>")
+
+		case tok.kind == texSub || tok.kind == texSup:
+			return "", fmt.Errorf("double %s", tok.text)
+
+		default:
+			return p.scripts(base, sub, sup, limits), nil
+		}
+	}
+}
+
+func (p *mathParser) scripts(base, sub string, sup []string, limits bool) string {
+	script := strings.Join(sup, "")
+	if len(sup) > 1 {
+		script = "<mrow>" + script + "</mrow>"
+	}
+
+	under, over, both := "msub", "msup", "msubsup"
+	if limits && p.display {
+		under, over, both = "munder", "mover", "munderover"
+	}
+	switch {
+	case sub != "" && script != "":
+		return fmt.Sprintf("<%s>%s%s%s</%s>", both, base, sub, script, both)
+	case sub != "":
+		return fmt.Sprintf("<%s>%s%s</%s>", under, base, sub, under)
+	case script != "":
+		return fmt.Sprintf("<%s>%s%s</%s>", over, base, script, over)
+	}
+
+	return base
+}
+
+// parseArgument parses the argument of a command or a script, a group or
+// a single token.
+func (p *mathParser) parseArgument() (string, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case texEOF, texClose, texSub, texSup, texAlign:
+		return "", fmt.Errorf("missing argument")
+
+	case texNumber:
+		// only the first digit of `x^23` is the script
+		if len(tok.text) > 1 {
+			p.tokens[p.pos].text = tok.text[1:]
+			return "<mn>" + tok.text[:1] + "</mn>", nil
+		}
+	}
+
+	elem, _, err := p.parseAtom()
+	return elem, err
+}
+
+func (p *mathParser) parseGroup() (string, error) {
+	if p.next().kind != texOpen {
+		return "", fmt.Errorf("missing {")
+	}
+
+	row, err := p.parseRow(func(texToken) bool { return false })
+	if err != nil {
+		return "", err
+	}
+	if p.next().kind != texClose {
+		return "", fmt.Errorf("missing }")
+	}
+
+	return "<mrow>" + row + "</mrow>", nil
+}
+
+// parseAtom parses one element, it reports whether the element takes its
+// scripts as limits.
+func (p *mathParser) parseAtom() (string, bool, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case texOpen:
+		elem, err := p.parseGroup()
+		return elem, false, err
+
+	case texSub, texSup:
+		// a script without base
+		return "<mrow></mrow>", false, nil
+
+	case texLetter:
+		p.next()
+		return p.identifier(tok.text), false, nil
+
+	case texNumber:
+		p.next()
+		return "<mn>" + tok.text + "</mn>", false, nil
+
+	case texOperator:
+		p.next()
+		return texOperatorElement(tok.text), false, nil
+
+	case texCommand:
+		p.next()
+		return p.parseCommand(tok.text)
+	}
+
+	return "", false, fmt.Errorf("unexpected %s", tok.text)
+}
+
+func (p *mathParser) identifier(name string) string {
+	variant := p.variant
+	if variant == "" && utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		if unicode.IsUpper(r) && r > unicode.MaxASCII {
+			variant = "normal"
+		}
+	}
+	if variant != "" && variant != "italic" {
+		return fmt.Sprintf("<mi mathvariant=\"%s\">%s</mi>", variant, escapeHTML([]byte(name)))
+	}
+
+	return "<mi>" + string(escapeHTML([]byte(name))) + "</mi>"
+}
+
+func texOperatorElement(op string) string {
+	switch op {
+	case "-":
+		op = "−"
+	case "*":
+		op = "∗"
+	case "~":
+		return "<mtext>&#160;</mtext>"
+	}
+
+	return "<mo>" + string(escapeHTML([]byte(op))) + "</mo>"
+}
+
+func (p *mathParser) parseCommand(name string) (string, bool, error) {
+	if r, ok := texGreek[name]; ok {
+		return p.identifier(r), false, nil
+	}
+	if r, ok := texIdentifiers[name]; ok {
+		return "<mi>" + r + "</mi>", false, nil
+	}
+	if r, ok := texOperators[name]; ok {
+		return "<mo>" + string(escapeHTML([]byte(r))) + "</mo>", false, nil
+	}
+	if r, ok := texBigOperators[name]; ok {
+		return "<mo movablelimits=\"true\">" + r + "</mo>", true, nil
+	}
+	if r, ok := texIntegrals[name]; ok {
+		return "<mo>" + r + "</mo>", false, nil
+	}
+	if texFunctions[name] {
+		return "<mi>" + name + "</mi>", false, nil
+	}
+	if texLimitFunctions[name] {
+		return "<mo movablelimits=\"true\">" + name + "</mo>", true, nil
+	}
+	if width, ok := texSpaces[name]; ok {
+		return fmt.Sprintf("<mspace width=\"%s\"/>", width), false, nil
+	}
+	if variant, ok := texTexts[name]; ok {
+		tok := p.next()
+		if tok.kind != texText {
+			return "", false, fmt.Errorf("missing argument of \\%s", name)
+		}
+		text := escapeHTML([]byte(tok.text))
+		if name == "operatorname" {
+			return "<mi>" + string(text) + "</mi>", false, nil
+		}
+		if variant != "" {
+			return fmt.Sprintf("<mtext mathvariant=\"%s\">%s</mtext>", variant, text), false, nil
+		}
+		return "<mtext>" + string(text) + "</mtext>", false, nil
+	}
+	if variant, ok := texFonts[name]; ok {
+		saved := p.variant
+		p.variant = variant
+		elem, err := p.parseArgument()
+		p.variant = saved
+		return elem, false, err
+	}
+	if accent, ok := texAccents[name]; ok {
+		elem, err := p.parseArgument()
+		return fmt.Sprintf("<mover accent=\"true\">%s<mo>%s</mo></mover>", elem, escapeHTML([]byte(accent))), false, err
+	}
+	if texSizes[name] {
+		return p.parseAtom()
+	}
+
+	switch name {
+	case " ":
+		return "<mtext>&#160;</mtext>", false, nil
+
+	case "frac", "dfrac", "tfrac", "binom":
+		num, err := p.parseArgument()
+		if err != nil {
+			return "", false, err
+		}
+		den, err := p.parseArgument()
+		if err != nil {
+			return "", false, err
+		}
+		if name == "binom" {
+			return fmt.Sprintf("<mrow><mo>(</mo><mfrac linethickness=\"0\">%s%s</mfrac><mo>)</mo></mrow>", num, den), false, nil
+		}
+		return fmt.Sprintf("<mfrac>%s%s</mfrac>", num, den), false, nil
+
+	case "sqrt":
+		var index string
+		if tok := p.peek(); tok.kind == texOperator && tok.text == "[" {
+			p.next()
+			row, err := p.parseRow(func(tok texToken) bool { return tok.kind == texOperator && tok.text == "]" })
+			if err != nil {
+				return "", false, err
+			}
+			if p.next().text != "]" {
+				return "", false, fmt.Errorf("missing ]")
+			}
+			index = row
+		}
+		elem, err := p.parseArgument()
+		if err != nil {
+			return "", false, err
+		}
+		if index != "" {
+			return fmt.Sprintf("<mroot>%s<mrow>%s</mrow></mroot>", elem, index), false, nil
+		}
+		return "<msqrt>" + elem + "</msqrt>", false, nil
+
+	case "underline":
+		elem, err := p.parseArgument()
+		return fmt.Sprintf("<munder accentunder=\"true\">%s<mo>_</mo></munder>", elem), false, err
+
+	case "overbrace", "underbrace":
+		elem, err := p.parseArgument()
+		if name == "overbrace" {
+			return fmt.Sprintf("<mover>%s<mo>⏞</mo></mover>", elem), true, err
+		}
+		return fmt.Sprintf("<munder>%s<mo>⏟</mo></munder>", elem), true, err
+
+	case "left":
+		open, err := p.parseDelimiter()
+		if err != nil {
+			return "", false, err
+		}
+		row, err := p.parseRow(func(tok texToken) bool { return tok.kind == texCommand && tok.text == "right" })
+		if err != nil {
+			return "", false, err
+		}
+		if p.next().text != "right" {
+			return "", false, fmt.Errorf("missing \\right")
+		}
+		closing, err := p.parseDelimiter()
+		if err != nil {
+			return "", false, err
+		}
+		return "<mrow>" + open + row + closing + "</mrow>", false, nil
+
+	case "middle":
+		elem, err := p.parseDelimiter()
+		return elem, false, err
+
+	case "begin":
+		elem, err := p.parseEnvironment()
+		return elem, false, err
+	}
+
+	return "", false, fmt.Errorf("unsupported command \\%s", name)
+}
+
+// parseDelimiter parses the delimiter after \left, \middle or \right, `.`
+// is an empty delimiter.
+func (p *mathParser) parseDelimiter() (string, error) {
+	tok := p.next()
+	var op string
+	switch tok.kind {
+	case texOperator:
+		op = tok.text
+		if op == "." {
+			return "", nil
+		}
+
+	case texCommand:
+		var ok bool
+		if op, ok = texOperators[tok.text]; !ok {
+			return "", fmt.Errorf("unsupported delimiter \\%s", tok.text)
+		}
+
+	default:
+		return "", fmt.Errorf("missing delimiter")
+	}
+
+	return "<mo fence=\"true\" stretchy=\"true\">" + string(escapeHTML([]byte(op))) + "</mo>", nil
+}
+
+// parseEnvironment parses the cells of a matrix, cases or aligned
+// environment into a table, rows end at `\\` and cells at `&`.
+func (p *mathParser) parseEnvironment() (string, error) {
+	name, err := p.parseName()
+	if err != nil {
+		return "", err
+	}
+	fences, ok := texFences[name]
+	if !ok {
+		return "", fmt.Errorf("unsupported environment %s", name)
+	}
+
+	endCell := func(tok texToken) bool {
+		return tok.kind == texAlign || tok.kind == texCommand && (tok.text == "\\" || tok.text == "end")
+	}
+
+	var table strings.Builder
+	table.WriteString("<mtable")
+	switch name {
+	case "cases":
+		table.WriteString(" columnalign=\"left left\"")
+	case "aligned", "align", "align*":
+		table.WriteString(" columnalign=\"right left\" columnspacing=\"0em\"")
+	}
+	table.WriteString("><mtr><mtd>")
+
+loop:
+	for {
+		row, err := p.parseRow(endCell)
+		if err != nil {
+			return "", err
+		}
+		table.WriteString(row)
+
+		tok := p.next()
+		switch {
+		case tok.kind == texAlign:
+			table.WriteString("</mtd><mtd>")
+
+		case tok.kind == texCommand && tok.text == "\\":
+			// a `\\` before \end does not start another row
+			if next := p.peek(); next.kind != texCommand || next.text != "end" {
+				table.WriteString("</mtd></mtr><mtr><mtd>")
+			}
+
+		case tok.kind == texCommand && tok.text == "end":
+			end, err := p.parseName()
+			if err != nil {
+				return "", err
+			}
+			if end != name {
+				return "", fmt.Errorf("\\begin{%s} ended by \\end{%s}", name, end)
+			}
+			break loop
+
+		default:
+			return "", fmt.Errorf("missing \\end{%s}", name)
+		}
+	}
+	table.WriteString("</mtd></mtr></mtable>")
+
+	result := table.String()
+	if fences[0] != "" {
+		result = "<mo fence=\"true\">" + string(escapeHTML([]byte(fences[0]))) + "</mo>" + result
+	}
+	if fences[1] != "" {
+		result += "<mo fence=\"true\">" + string(escapeHTML([]byte(fences[1]))) + "</mo>"
+	}
+
+	return "<mrow>" + result + "</mrow>", nil
+}
+
+// parseName parses the `{name}` of an environment.
+func (p *mathParser) parseName() (string, error) {
+	if p.next().kind != texOpen {
+		return "", fmt.Errorf("missing environment name")
+	}
+
+	var name string
+	for {
+		tok := p.next()
+		switch tok.kind {
+		case texClose:
+			return name, nil
+		case texLetter, texOperator:
+			name += tok.text
+		default:
+			return "", fmt.Errorf("invalid environment name")
+		}
+	}
+}
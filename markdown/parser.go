@@ -0,0 +1,472 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+)
+
+// line is one source line, col is the 1-based column of text[0] in the
+// original input, so positions survive stripping of container prefixes.
+type line struct {
+	text []byte
+	num  int
+	col  int
+	// lazy marks a paragraph continuation without the prefix of its
+	// container, which can not underline a heading
+	lazy bool
+}
+
+type blockParser struct {
+	ext   *Parser
+	lines []line
+	pos   int
+	// linkDef is the definition found by isLinkDefinition at the line
+	// linkDefAt, with the number of its lines
+	linkDef      *LinkDefinition
+	linkDefAt    int
+	linkDefLines int
+}
+
+// Parse splits input into lines and builds the block tree of it, with the
+// syntax of the package only.
+func Parse(input []byte) *Document {
+	return defaultParser.Parse(input)
+}
+
+// Parse splits input into lines and builds the block tree of it, with the
+// block and inline parsers registered on p.
+func (p *Parser) Parse(input []byte) *Document {
+	input = bytes.Replace(input, []byte("\r\n"), lineTrail, -1)
+	input = bytes.TrimSuffix(input, lineTrail)
+
+	src := bytes.Split(input, lineTrail)
+	lines := make([]line, len(src))
+	for i, text := range src {
+		lines[i] = line{text: text, num: i + 1, col: 1}
+	}
+
+	doc := &Document{
+		Position: Position{Line: 1, Column: 1},
+		Blocks:   p.parseBlocks(lines),
+	}
+	if p.extensions&HeadingIDs != 0 {
+		splitHeadingIDs(doc)
+	}
+	parseInlines(doc, p)
+	if p.extensions&Abbreviations != 0 {
+		abbreviate(doc)
+	}
+	assignHeadingIDs(doc)
+
+	return doc
+}
+
+func (ext *Parser) parseBlocks(lines []line) []Node {
+	p := &blockParser{ext: ext, lines: lines}
+
+	var blocks []Node
+	for p.pos < len(p.lines) {
+		if isBlank(p.peek().text) {
+			p.pos++
+			continue
+		}
+		blocks = append(blocks, p.parseBlock())
+	}
+
+	return blocks
+}
+
+func (p *blockParser) peek() line {
+	return p.lines[p.pos]
+}
+
+func (p *blockParser) parseBlock() Node {
+	l := p.peek()
+
+	if p.opens(p.pos) {
+		if node := p.parseCustom(); node != nil {
+			return node
+		}
+	}
+
+	if startsText(l.text) {
+		if p.isTableStart(p.pos) {
+			return p.parseTable()
+		}
+		if p.isDefinitionList(p.pos) {
+			return p.parseDefinitionList()
+		}
+		return p.parseParagraph()
+	}
+
+	switch {
+	case isFence(l.text):
+		return p.parseFencedCode()
+
+	case reHeader.Match(l.text):
+		return p.parseHeading()
+
+	case reBreak.Match(l.text):
+		p.pos++
+		return &ThematicBreak{Position: Position{Line: l.num, Column: l.col + len(leadingSpace(l.text))}}
+
+	case p.isMathBlock(p.pos):
+		return p.parseMathBlock()
+
+	case indentWidth(leadingSpace(l.text)) >= 4:
+		return p.parseIndentedCode()
+
+	case reQuote.Match(l.text):
+		return p.parseQuote()
+
+	case reAdmonition.Match(l.text):
+		return p.parseAdmonition()
+
+	case htmlBlockKind(l.text) > 0:
+		return p.parseHTMLBlock()
+
+	case reFootnoteDef.Match(l.text):
+		return p.parseFootnoteDefinition()
+
+	case p.isLinkDefinition(p.pos):
+		return p.parseLinkDefinition()
+
+	case p.isAbbreviationDefinition(p.pos):
+		return p.parseAbbreviationDefinition()
+
+	case p.isTableStart(p.pos):
+		return p.parseTable()
+
+	case reList.Match(l.text):
+		return p.parseList()
+
+	case p.isDefinitionList(p.pos):
+		return p.parseDefinitionList()
+	}
+
+	return p.parseParagraph()
+}
+
+// interrupts reports whether the line at index i starts a block which
+// ends the paragraph before it.
+func (p *blockParser) interrupts(i int) bool {
+	text := p.lines[i].text
+	if indentWidth(leadingSpace(text)) >= 4 {
+		return false
+	}
+
+	if startsText(text) {
+		return p.opens(i) || p.isTableStart(i)
+	}
+
+	if isFence(text) || reHeader.Match(text) || reBreak.Match(text) || reQuote.Match(text) || reAdmonition.Match(text) || reFootnoteDef.Match(text) {
+		return true
+	}
+
+	if kind := htmlBlockKind(text); kind > 0 && kind < 7 {
+		return true
+	}
+
+	if p.isMathBlock(i) || p.isAbbreviationDefinition(i) || p.opens(i) {
+		return true
+	}
+
+	if ret := reList.FindSubmatch(text); ret != nil && len(bytes.TrimSpace(ret[4])) > 0 {
+		ordered, start := listMarker(ret[2])
+		if !ordered || start == 1 {
+			return true
+		}
+	}
+
+	return p.isTableStart(i)
+}
+
+func (p *blockParser) parseHeading() Node {
+	l := p.peek()
+	p.pos++
+
+	ret := reHeader.FindSubmatchIndex(l.text)
+	heading := &Heading{
+		Position: Position{Line: l.num, Column: l.col + ret[2]},
+		Level:    ret[3] - ret[2],
+	}
+	// the closing sequence of `### ###` is matched as the text
+	if ret[4] >= 0 && len(bytes.Trim(l.text[ret[4]:ret[5]], "# \t")) > 0 {
+		heading.Text = bytes.TrimSpace(l.text[ret[4]:ret[5]])
+	}
+
+	return heading
+}
+
+func (p *blockParser) parseFencedCode() Node {
+	l := p.peek()
+	p.pos++
+
+	ret := reFence.FindSubmatchIndex(l.text)
+	indent := ret[3] - ret[2]
+	fence := l.text[ret[4]:ret[5]]
+	info := unescapeString(bytes.TrimSpace(l.text[ret[6]:ret[7]]))
+
+	block := &CodeBlock{
+		Position: Position{Line: l.num, Column: l.col + ret[4]},
+		Fenced:   true,
+		Info:     info,
+	}
+	if fields := strings.Fields(info); len(fields) > 0 {
+		block.Lang = fields[0]
+	}
+
+	var codes [][]byte
+	for p.pos < len(p.lines) {
+		text := p.peek().text
+		p.pos++
+		if isClosingFence(text, fence) {
+			break
+		}
+		codes = append(codes, stripIndent(text, indent))
+	}
+	block.Code = bytes.Join(codes, lineTrail)
+
+	if p.ext != nil && p.ext.extensions&Diagrams != 0 && (block.Lang == "mermaid" || block.Lang == "dot") {
+		return &Diagram{Position: block.Position, Lang: block.Lang, Source: block.Code}
+	}
+
+	return block
+}
+
+func (p *blockParser) parseIndentedCode() Node {
+	l := p.peek()
+
+	var codes [][]byte
+	end := p.pos
+	for i := p.pos; i < len(p.lines); i++ {
+		text := p.lines[i].text
+		if isBlank(text) {
+			codes = append(codes, stripIndent(text, 4))
+			continue
+		}
+		if indentWidth(leadingSpace(text)) < 4 {
+			break
+		}
+		codes = append(codes, stripIndent(text, 4))
+		end = i + 1
+	}
+
+	// trailing blank lines are not part of the code
+	codes = codes[:end-p.pos]
+	p.pos = end
+
+	return &CodeBlock{
+		Position: Position{Line: l.num, Column: l.col + 4},
+		Code:     bytes.Join(codes, lineTrail),
+	}
+}
+
+func (p *blockParser) parseQuote() Node {
+	first := p.peek()
+
+	var lines []line
+	var para openParagraph
+	for p.pos < len(p.lines) {
+		l := p.peek()
+		if ret := reQuote.FindIndex(l.text); ret != nil {
+			lines = append(lines, line{text: l.text[ret[1]:], num: l.num, col: l.col + ret[1]})
+			para.add(lines[len(lines)-1].text)
+			p.pos++
+			continue
+		}
+
+		// lazy continuation of a paragraph inside the quote
+		if isBlank(l.text) || p.interrupts(p.pos) || !para.accepts(p, lines) {
+			break
+		}
+		l.lazy = true
+		lines = append(lines, l)
+		p.pos++
+	}
+
+	quote := &Quote{
+		Position: Position{Line: first.num, Column: first.col + len(leadingSpace(first.text))},
+	}
+	if alert := p.alert(quote, lines); alert != quote {
+		return alert
+	}
+	quote.Blocks = p.ext.parseBlocks(lines)
+
+	return quote
+}
+
+// endsInParagraph reports whether the last block of lines is a paragraph,
+// which may be continued by a lazy line.
+func (p *blockParser) endsInParagraph(lines []line) bool {
+	if len(lines) == 0 || isBlank(lines[len(lines)-1].text) {
+		return false
+	}
+
+	blocks := p.ext.parseBlocks(lines)
+	for len(blocks) > 0 {
+		switch n := blocks[len(blocks)-1].(type) {
+		case *Paragraph, *Image:
+			return true
+
+		case *Quote:
+			blocks = n.Blocks
+
+		case *Admonition:
+			if n.Fenced {
+				return false
+			}
+			blocks = n.Blocks
+
+		case *List:
+			blocks = n.Items[len(n.Items)-1].Blocks
+
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+// openParagraph remembers whether the lines gathered for a container end in
+// a paragraph, so a run of lazy lines doesn't reparse all of them again.
+type openParagraph struct {
+	known, open bool
+}
+
+// add records a line appended to the container. Plain text, maybe after
+// quote or list markers, either continues the open paragraph or starts a
+// new one, any other line needs a reparse.
+func (o *openParagraph) add(text []byte) {
+	for {
+		if ret := reQuote.FindIndex(text); ret != nil {
+			text = text[ret[1]:]
+		} else if ret := reList.FindSubmatchIndex(text); ret != nil {
+			text = text[ret[6]:]
+		} else {
+			break
+		}
+	}
+	if !o.open || !startsText(text) || bytes.ContainsAny(text, "'\"()|") {
+		o.known = false
+	}
+}
+
+// accepts reports whether a lazy line may continue the paragraph which ends
+// lines, once accepted the paragraph stays open.
+func (o *openParagraph) accepts(p *blockParser, lines []line) bool {
+	if !o.known {
+		o.known, o.open = true, p.endsInParagraph(lines)
+	}
+	return o.open
+}
+
+// parseParagraph consumes the lines up to a blank line or the start of
+// another block, a paragraph underlined by `===` or `---` is a heading.
+func (p *blockParser) parseParagraph() Node {
+	first := p.peek()
+	pos := Position{Line: first.num, Column: first.col + len(leadingSpace(first.text))}
+
+	var texts [][]byte
+	for p.pos < len(p.lines) {
+		l := p.peek()
+		if isBlank(l.text) {
+			break
+		}
+		if len(texts) > 0 {
+			if ret := reSetext.FindSubmatch(l.text); ret != nil && !l.lazy {
+				p.pos++
+				level := 2
+				if ret[1][0] == '=' {
+					level = 1
+				}
+				return &Heading{Position: pos, Level: level, Text: bytes.TrimSpace(bytes.Join(texts, lineTrail))}
+			}
+			if p.interrupts(p.pos) {
+				break
+			}
+		}
+		texts = append(texts, bytes.TrimLeft(l.text, " \t"))
+		p.pos++
+	}
+
+	text := bytes.TrimRight(bytes.Join(texts, lineTrail), " \t")
+	if ret := reImage.FindSubmatch(text); ret != nil {
+		img := &Image{Position: pos, Alt: ret[1], Src: bytes.Trim(ret[2], "<>")}
+		if len(ret[3]) > 1 {
+			img.Title = ret[3][1 : len(ret[3])-1]
+		}
+		return img
+	}
+
+	return &Paragraph{Position: pos, Text: text}
+}
+
+func isBlank(text []byte) bool {
+	return len(bytes.TrimSpace(text)) == 0
+}
+
+// isFence reports whether text opens a fenced code block, the info string
+// of a backtick fence may not contain backticks.
+func isFence(text []byte) bool {
+	ret := reFence.FindSubmatch(text)
+	if ret == nil {
+		return false
+	}
+
+	return ret[2][0] == '~' || !bytes.Contains(ret[3], []byte("`"))
+}
+
+func isClosingFence(text, fence []byte) bool {
+	ret := reFence.FindSubmatch(text)
+	if ret == nil || len(bytes.TrimSpace(ret[3])) > 0 {
+		return false
+	}
+
+	return ret[2][0] == fence[0] && len(ret[2]) >= len(fence)
+}
+
+// startsText reports whether text starts with a letter or a character
+// outside ASCII after less than four spaces, such a line starts a
+// paragraph or a table.
+func startsText(text []byte) bool {
+	space := leadingSpace(text)
+	if len(space) == len(text) || indentWidth(space) >= 4 {
+		return false
+	}
+
+	c := text[len(space)]
+	return isLetter(c) || c >= 0x80
+}
+
+func leadingSpace(text []byte) []byte {
+	return text[:len(text)-len(bytes.TrimLeft(text, " \t"))]
+}
+
+// stripIndent removes up to width columns of leading whitespace, a tab
+// that straddles the boundary is kept as spaces.
+func stripIndent(text []byte, width int) []byte {
+	col := 0
+	for i, c := range text {
+		if col >= width {
+			return text[i:]
+		}
+
+		switch c {
+		case ' ':
+			col++
+		case '\t':
+			next := col + 4 - col%4
+			if next > width {
+				pad := bytes.Repeat([]byte(" "), next-width)
+				return append(pad, text[i+1:]...)
+			}
+			col = next
+		default:
+			return text[i:]
+		}
+	}
+
+	return text[len(text):]
+}
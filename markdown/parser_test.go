@@ -0,0 +1,121 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestParseBlocks(t *testing.T) {
+	input := "# Title\nfirst line\n\n```go\nfunc main() {\n\n}\n```\n\n> quote\n> - item\n\n    indented"
+	doc := Parse([]byte(input))
+
+	if len(doc.Blocks) != 5 {
+		t.Fatalf("Parse fail, %d blocks vs 5", len(doc.Blocks))
+	}
+
+	heading, ok := doc.Blocks[0].(*Heading)
+	if !ok || heading.Level != 1 || string(heading.Text) != "Title" {
+		t.Fatalf("Parse fail, invalid heading %+v", doc.Blocks[0])
+	}
+
+	para, ok := doc.Blocks[1].(*Paragraph)
+	if !ok || string(para.Text) != "first line" {
+		t.Fatalf("Parse fail, invalid paragraph %+v", doc.Blocks[1])
+	}
+
+	code, ok := doc.Blocks[2].(*CodeBlock)
+	if !ok || code.Lang != "go" || string(code.Code) != "func main() {\n\n}" {
+		t.Fatalf("Parse fail, invalid code block %+v", doc.Blocks[2])
+	}
+
+	quote, ok := doc.Blocks[3].(*Quote)
+	if !ok || len(quote.Blocks) != 2 {
+		t.Fatalf("Parse fail, invalid quote %+v", doc.Blocks[3])
+	}
+	if _, ok := quote.Blocks[1].(*List); !ok {
+		t.Fatalf("Parse fail, invalid list in quote %+v", quote.Blocks[1])
+	}
+
+	indented, ok := doc.Blocks[4].(*CodeBlock)
+	if !ok || indented.Fenced || string(indented.Code) != "indented" {
+		t.Fatalf("Parse fail, invalid indented code %+v", doc.Blocks[4])
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	input := "para\n\n> ## quoted\n\n1. one\n   - nested"
+	doc := Parse([]byte(input))
+
+	var positions []Position
+	Walk(doc, func(node Node) bool {
+		switch node.(type) {
+		case *Heading, *List, *Paragraph:
+			positions = append(positions, node.Pos())
+		}
+		return true
+	})
+
+	output := []Position{
+		{Line: 1, Column: 1},
+		{Line: 3, Column: 3},
+		{Line: 5, Column: 1},
+		{Line: 5, Column: 4},
+		{Line: 6, Column: 4},
+		{Line: 6, Column: 6},
+	}
+	if len(positions) != len(output) {
+		t.Fatalf("Parse position fail, %v vs %v", positions, output)
+	}
+	for i, pos := range positions {
+		if pos != output[i] {
+			t.Fatalf("Parse position fail, %v vs %v", positions, output)
+		}
+	}
+}
+
+func TestParseLooseList(t *testing.T) {
+	input := [][]byte{
+		[]byte("- a\n\n- b"),
+		[]byte("- a\n\n  second paragraph\n- b"),
+		[]byte("- a\n- b\n\nnext paragraph"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<ul>\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ul>\n"),
+		[]byte("\n<ul>\n<li>\n<p>a</p>\n\n<p>second paragraph</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ul>\n"),
+		[]byte("\n<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n\n<p>next paragraph</p>\n"),
+	}
+	for i, v := range input {
+		result := Render(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("ParseLooseList fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
+func TestDeepLazyContinuation(t *testing.T) {
+	input := []string{"> > > start\n", "- > - start\n", "> > > start\n"}
+	lines := []string{"lazy %[1]d\n", "lazy %[1]d\n", "> > > a %[1]d\nlazy %[1]d\n"}
+	texts := []string{"lazy %[1]d\n", "lazy %[1]d\n", "a %[1]d\nlazy %[1]d\n"}
+	output := []string{
+		"\n<blockquote>\n<blockquote>\n<blockquote>\n<p>start\n%s</p>\n</blockquote>\n</blockquote>\n</blockquote>\n",
+		"\n<ul>\n<li>\n<blockquote>\n<ul>\n<li>start\n%s</li>\n</ul>\n</blockquote>\n</li>\n</ul>\n",
+		"\n<blockquote>\n<blockquote>\n<blockquote>\n<p>start\n%s</p>\n</blockquote>\n</blockquote>\n</blockquote>\n",
+	}
+
+	for i, v := range input {
+		source := bytes.NewBufferString(v)
+		var text bytes.Buffer
+		for j := 0; j < 5000; j++ {
+			fmt.Fprintf(source, lines[i], j)
+			fmt.Fprintf(&text, texts[i], j)
+		}
+
+		result := Render(source.Bytes())
+		expected := fmt.Sprintf(output[i], bytes.TrimSuffix(text.Bytes(), []byte("\n")))
+		if string(result) != expected {
+			t.Fatalf("deep lazy continuation fail, [%.200s] vs [%.200s]", result, expected)
+		}
+	}
+}
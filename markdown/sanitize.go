@@ -0,0 +1,251 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// HTMLMode decides what happens to raw HTML written in the source.
+type HTMLMode int
+
+const (
+	// HTMLSanitize keeps the tags and attributes allowed by the policy,
+	// and escapes everything else. It is the default mode.
+	HTMLSanitize HTMLMode = iota
+	// HTMLEscape escapes all raw HTML, so it shows up as text.
+	HTMLEscape
+	// HTMLUnsafe copies raw HTML to the output as it is.
+	HTMLUnsafe
+)
+
+// Policy is an allow-list of raw HTML tags, mapped to the attributes
+// allowed on them. URL attributes are always checked for unsafe schemes.
+type Policy struct {
+	Tags map[string][]string
+}
+
+var DefaultPolicy = &Policy{
+	Tags: map[string][]string{
+		"a":          {"href", "title", "name"},
+		"abbr":       {"title"},
+		"b":          nil,
+		"blockquote": {"cite"},
+		"br":         nil,
+		"code":       nil,
+		"dd":         nil,
+		"del":        nil,
+		"details":    nil,
+		"div":        {"class"},
+		"dl":         nil,
+		"dt":         nil,
+		"em":         nil,
+		"figcaption": nil,
+		"figure":     nil,
+		"h1":         {"id"},
+		"h2":         {"id"},
+		"h3":         {"id"},
+		"h4":         {"id"},
+		"h5":         {"id"},
+		"h6":         {"id"},
+		"hr":         nil,
+		"i":          nil,
+		"img":        {"src", "alt", "title", "width", "height"},
+		"ins":        nil,
+		"kbd":        nil,
+		"li":         nil,
+		"mark":       nil,
+		"ol":         {"start"},
+		"p":          nil,
+		"pre":        {"lang"},
+		"s":          nil,
+		"small":      nil,
+		"span":       {"class"},
+		"strong":     nil,
+		"sub":        nil,
+		"summary":    nil,
+		"sup":        nil,
+		"table":      nil,
+		"tbody":      nil,
+		"td":         {"align"},
+		"th":         {"align"},
+		"thead":      nil,
+		"tr":         nil,
+		"u":          nil,
+		"ul":         nil,
+	},
+}
+
+var (
+	reHTMLTag  *regexp.Regexp
+	reHTMLAttr *regexp.Regexp
+	reEntity   *regexp.Regexp
+)
+
+var urlAttrs = map[string]bool{
+	"href": true,
+	"src":  true,
+	"cite": true,
+}
+
+func init() {
+	attr := `[a-zA-Z_:][-a-zA-Z0-9_:.]*(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?`
+	reHTMLTag = regexp.MustCompile(`^(?:<(/?)([a-zA-Z][a-zA-Z0-9-]*)((?:\s+` + attr + `)*)\s*(/?)>|<!-{2,3}>|<!--[\s\S]*?-->|<\?[\s\S]*?\?>|<![a-zA-Z][^>]*>|<!\[CDATA\[[\s\S]*?\]\]>)`)
+	reHTMLAttr = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
+	reEntity = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+}
+
+// escapeHTML escapes every special character of input, it is used for code
+// where entity references are literal text.
+func escapeHTML(input []byte) []byte {
+	if bytes.IndexAny(input, "&<>\"") < 0 {
+		return input
+	}
+
+	var buffer bytes.Buffer
+	for _, c := range input {
+		writeEscaped(&buffer, c)
+	}
+
+	return buffer.Bytes()
+}
+
+// rawHTML handles the tag matched by reHTMLTag at the start of input.
+func rawHTML(input []byte, ret []int, opts *Options) []byte {
+	tag := input[:ret[1]]
+
+	switch opts.HTML {
+	case HTMLUnsafe:
+		return tag
+
+	case HTMLEscape:
+		return escapeHTML(tag)
+	}
+
+	// comments, declarations and processing instructions are dropped
+	if ret[4] < 0 {
+		return nil
+	}
+
+	policy := opts.Policy
+	if policy == nil {
+		policy = DefaultPolicy
+	}
+
+	name := strings.ToLower(string(input[ret[4]:ret[5]]))
+	allowed, ok := policy.Tags[name]
+	if !ok {
+		return escapeHTML(tag)
+	}
+
+	if ret[3] > ret[2] {
+		return []byte(fmt.Sprintf("</%s>", name))
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("<" + name)
+	for _, attr := range reHTMLAttr.FindAllSubmatch(input[ret[6]:ret[7]], -1) {
+		key := strings.ToLower(string(attr[1]))
+		if !containsString(allowed, key) {
+			continue
+		}
+
+		value := html.UnescapeString(string(attr[2]) + string(attr[3]) + string(attr[4]))
+		if urlAttrs[key] && !SafeURL(value, name == "img") {
+			continue
+		}
+		buffer.WriteString(fmt.Sprintf(" %s=\"%s\"", key, escapeHTML([]byte(value))))
+	}
+	if ret[9] > ret[8] {
+		buffer.WriteString(" /")
+	}
+	buffer.WriteString(">")
+
+	return buffer.Bytes()
+}
+
+// SafeURL reports whether the url does not use a scheme which runs
+// script, data URLs are only allowed for images.
+func SafeURL(url string, image bool) bool {
+	url = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, url)
+	url = strings.ToLower(url)
+
+	switch {
+	case strings.HasPrefix(url, "javascript:"), strings.HasPrefix(url, "vbscript:"):
+		return false
+
+	case strings.HasPrefix(url, "data:"):
+		if !image {
+			return false
+		}
+		for _, tp := range []string{"png", "gif", "jpeg", "webp"} {
+			if strings.HasPrefix(url, "data:image/"+tp+";") {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// normalizeURL percent-encodes the characters of url which may not appear
+// in an URL, the percent-encoded bytes of url are kept as they are.
+func normalizeURL(url string) string {
+	var builder strings.Builder
+	for i := 0; i < len(url); i++ {
+		c := url[i]
+		switch {
+		case c == '%' && i+2 < len(url) && isHexDigit(url[i+1]) && isHexDigit(url[i+2]):
+			builder.WriteByte(c)
+
+		case c < 0x80 && (isAlnum(c) || strings.IndexByte("-_.+!*'(),#@?=;:/$~&", c) >= 0):
+			builder.WriteByte(c)
+
+		default:
+			builder.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+
+	return builder.String()
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func isAlnum(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func writeEscaped(buffer *bytes.Buffer, c byte) {
+	switch c {
+	case '&':
+		buffer.WriteString("&amp;")
+	case '<':
+		buffer.WriteString("&lt;")
+	case '>':
+		buffer.WriteString("&gt;")
+	case '"':
+		buffer.WriteString("&quot;")
+	default:
+		buffer.WriteByte(c)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
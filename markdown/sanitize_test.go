@@ -0,0 +1,80 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	input := [][]byte{
+		[]byte("if a < b && c > d"),
+		[]byte("AT&amp;T &copy; 2017"),
+		[]byte("```go\nif a < b {\n\tfmt.Println(\"&amp;\")\n}\n```"),
+		[]byte("use `<div>` here"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<p>if a &lt; b &amp;&amp; c &gt; d</p>\n"),
+		[]byte("\n<p>AT&amp;T © 2017</p>\n"),
+		[]byte("\n<pre lang=\"go\">\n<code>\nif a &lt; b {\n\tfmt.Println(&quot;&amp;amp;&quot;)\n}\n</code>\n</pre>\n"),
+		[]byte("\n<p>use <code>&lt;div&gt;</code> here</p>\n"),
+	}
+
+	for i, v := range input {
+		result := Render(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("Escape fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	input := [][]byte{
+		[]byte("<script>alert(1)</script>"),
+		[]byte("x<sup>2</sup> <br/>"),
+		[]byte("<a href=\"javascript:alert(1)\" onclick=\"x()\" title=\"t\">a</a>"),
+		[]byte("<img src=\"java\tscript:alert(1)\" alt=\"x\"><!-- hidden -->"),
+		[]byte("[click](javascript:alert(1)) [ok](http://hackcv.com)"),
+		[]byte("![x](javascript:alert(1))"),
+	}
+
+	output := [][]byte{
+		[]byte("\n&lt;script&gt;alert(1)&lt;/script&gt;\n"),
+		[]byte("\n<p>x<sup>2</sup> <br /></p>\n"),
+		[]byte("\n<p><a title=\"t\">a</a></p>\n"),
+		[]byte("\n<p><img alt=\"x\"></p>\n"),
+		[]byte("\n<p>click <a href=\"http://hackcv.com\">ok</a></p>\n"),
+		[]byte("\n<p>x</p>\n"),
+	}
+
+	for i, v := range input {
+		result := Render(v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("Sanitize fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
+
+func TestHTMLMode(t *testing.T) {
+	input := []byte("<span class=\"x\" style=\"color: red\">hi</span>")
+	opts := []Options{
+		{HTML: HTMLSanitize},
+		{HTML: HTMLEscape},
+		{HTML: HTMLUnsafe},
+		{HTML: HTMLSanitize, Policy: &Policy{Tags: map[string][]string{"span": {"style"}}}},
+	}
+
+	output := [][]byte{
+		[]byte("\n<p><span class=\"x\">hi</span></p>\n"),
+		[]byte("\n<p>&lt;span class=&quot;x&quot; style=&quot;color: red&quot;&gt;hi&lt;/span&gt;</p>\n"),
+		[]byte("\n<p><span class=\"x\" style=\"color: red\">hi</span></p>\n"),
+		[]byte("\n<p><span style=\"color: red\">hi</span></p>\n"),
+	}
+
+	for i, v := range opts {
+		result := RenderWithOptions(input, v)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("HTMLMode fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+}
@@ -0,0 +1,498 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"unicode"
+)
+
+// the sizes of diagrams are in pixels, text is measured with a fixed
+// width per character since the font is not known at build time
+const (
+	svgMargin    = 10
+	svgFontSize  = 14
+	svgBoxHeight = 36
+	svgRankGap   = 50
+	svgNodeGap   = 30
+	svgStroke    = "#333"
+)
+
+// textWidth is the estimated width of text, wide characters such as Han
+// take a full em.
+func textWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		if r >= 0x1100 && (unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || 0xff00 <= r && r <= 0xffef) {
+			width += svgFontSize
+		} else {
+			width += svgFontSize * 4 / 7
+		}
+	}
+
+	return width
+}
+
+func svgOpen(b *bytes.Buffer, width, height int) {
+	b.WriteString(fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"sans-serif\" font-size=\"%d\" role=\"img\">\n",
+		width, height, width, height, svgFontSize))
+}
+
+func svgText(b *bytes.Buffer, x, y int, text string) {
+	b.WriteString(fmt.Sprintf("<text x=\"%d\" y=\"%d\" text-anchor=\"middle\" dominant-baseline=\"central\">", x, y))
+	b.Write(escapeHTML([]byte(text)))
+	b.WriteString("</text>\n")
+}
+
+func svgLine(b *bytes.Buffer, x1, y1, x2, y2 int, dashed bool) {
+	b.WriteString(fmt.Sprintf("<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"%s\"", x1, y1, x2, y2, svgStroke))
+	if dashed {
+		b.WriteString(" stroke-dasharray=\"4 3\"")
+	}
+	b.WriteString("/>\n")
+}
+
+// svgArrow draws an arrowhead at x2, y2 pointing away from x1, y1.
+func svgArrow(b *bytes.Buffer, x1, y1, x2, y2 int) {
+	dx, dy := float64(x2-x1), float64(y2-y1)
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	ux, uy := dx/length, dy/length
+	bx, by := float64(x2)-10*ux, float64(y2)-10*uy
+
+	b.WriteString(fmt.Sprintf("<polygon points=\"%d,%d %d,%d %d,%d\" fill=\"%s\"/>\n", x2, y2,
+		round(bx-4*uy), round(by+4*ux), round(bx+4*uy), round(by-4*ux), svgStroke))
+}
+
+// svgLabel draws text on a white background, so it stays readable over
+// lines.
+func svgLabel(b *bytes.Buffer, x, y int, text string) {
+	w := textWidth(text) + 8
+	b.WriteString(fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"#fff\"/>\n", x-w/2, y-10, w, 20))
+	svgText(b, x, y, text)
+}
+
+func round(f float64) int {
+	return int(math.Round(f))
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// rank puts every node one rank after the furthest node with an edge to
+// it, edges which close a cycle are ignored. The nodes of a rank are then
+// ordered by the average order of the nodes linking to them.
+func (f *flowchart) rank() [][]*flowNode {
+	out := make(map[*flowNode][]*flowEdge)
+	for _, e := range f.edges {
+		out[e.from] = append(out[e.from], e)
+	}
+
+	const (
+		open = iota + 1
+		done
+	)
+	state := make(map[*flowNode]int)
+	back := make(map[*flowEdge]bool)
+	var order []*flowNode
+	var visit func(n *flowNode)
+	visit = func(n *flowNode) {
+		state[n] = open
+		for _, e := range out[n] {
+			switch state[e.to] {
+			case 0:
+				visit(e.to)
+			case open:
+				back[e] = true
+			}
+		}
+		state[n] = done
+		order = append(order, n)
+	}
+	for _, n := range f.nodes {
+		if state[n] == 0 {
+			visit(n)
+		}
+	}
+
+	for i := len(order) - 1; i >= 0; i-- {
+		for _, e := range out[order[i]] {
+			if !back[e] && e.to.rank < e.from.rank+1 {
+				e.to.rank = e.from.rank + 1
+			}
+		}
+	}
+
+	var ranks [][]*flowNode
+	for _, n := range f.nodes {
+		for len(ranks) <= n.rank {
+			ranks = append(ranks, nil)
+		}
+		n.order = len(ranks[n.rank])
+		ranks[n.rank] = append(ranks[n.rank], n)
+	}
+
+	for _, rank := range ranks[1:] {
+		keys := make(map[*flowNode]float64)
+		for _, n := range rank {
+			sum, count := 0, 0
+			for _, e := range f.edges {
+				if e.to == n && e.from.rank < n.rank {
+					sum += e.from.order
+					count++
+				}
+			}
+			keys[n] = float64(n.order)
+			if count > 0 {
+				keys[n] = float64(sum) / float64(count)
+			}
+		}
+		sort.SliceStable(rank, func(i, j int) bool { return keys[rank[i]] < keys[rank[j]] })
+		for i, n := range rank {
+			n.order = i
+		}
+	}
+
+	return ranks
+}
+
+// layout places the centers of the nodes, it returns the size of the
+// chart.
+func (f *flowchart) layout() (int, int) {
+	for _, n := range f.nodes {
+		w := textWidth(n.label)
+		n.w, n.h = maxInt(w+24, 60), svgBoxHeight
+		if n.shape == '{' {
+			n.w, n.h = w*3/2+40, 56
+		}
+	}
+
+	ranks := f.rank()
+	// the extent of a rank across the flow and its depth along it
+	extents := make([]int, len(ranks))
+	depths := make([]int, len(ranks))
+	widest := 0
+	for i, rank := range ranks {
+		for j, n := range rank {
+			across, along := n.w, n.h
+			if !f.vertical {
+				across, along = n.h, n.w
+			}
+			if j > 0 {
+				extents[i] += svgNodeGap
+			}
+			extents[i] += across
+			depths[i] = maxInt(depths[i], along)
+		}
+		widest = maxInt(widest, extents[i])
+	}
+
+	pos := svgMargin
+	for i, rank := range ranks {
+		start := svgMargin + (widest-extents[i])/2
+		for _, n := range rank {
+			if f.vertical {
+				n.x, n.y = start+n.w/2, pos+depths[i]/2
+				start += n.w + svgNodeGap
+			} else {
+				n.x, n.y = pos+depths[i]/2, start+n.h/2
+				start += n.h + svgNodeGap
+			}
+		}
+		pos += depths[i] + svgRankGap
+	}
+	pos += svgMargin - svgRankGap
+
+	if f.vertical {
+		return widest + 2*svgMargin, pos
+	}
+	return pos, widest + 2*svgMargin
+}
+
+// bend is the control point of a curved edge, which is 60 pixels to the
+// side of the middle of its ends.
+func (e *flowEdge) bend() (int, int) {
+	dx, dy := float64(e.to.x-e.from.x), float64(e.to.y-e.from.y)
+	length := math.Hypot(dx, dy)
+
+	return (e.from.x+e.to.x)/2 + round(-dy/length*60), (e.from.y+e.to.y)/2 + round(dx/length*60)
+}
+
+// clip is the point where the line from the center of n to x, y leaves its
+// shape.
+func (n *flowNode) clip(x, y int) (int, int) {
+	dx, dy := float64(x-n.x), float64(y-n.y)
+	hw, hh := float64(n.w)/2, float64(n.h)/2
+	if dx == 0 && dy == 0 {
+		return n.x, n.y
+	}
+
+	var t float64
+	if n.shape == '{' {
+		t = 1 / (math.Abs(dx)/hw + math.Abs(dy)/hh)
+	} else {
+		t = math.Inf(1)
+		if dx != 0 {
+			t = hw / math.Abs(dx)
+		}
+		if dy != 0 {
+			t = math.Min(t, hh/math.Abs(dy))
+		}
+	}
+
+	return n.x + round(t*dx), n.y + round(t*dy)
+}
+
+func (f *flowchart) svg() []byte {
+	width, height := f.layout()
+
+	// curved edges may bulge out of the chart, which then grows
+	left, top := 0, 0
+	for _, e := range f.edges {
+		if e.from == e.to {
+			width = maxInt(width, e.from.x+e.from.w/2+30+textWidth(e.label)+svgMargin)
+			continue
+		}
+		if e.to.rank <= e.from.rank {
+			cx, cy := e.bend()
+			x, y := (e.from.x+2*cx+e.to.x)/4, (e.from.y+2*cy+e.to.y)/4
+			left, top = minInt(left, x-svgMargin), minInt(top, y-svgMargin)
+			width, height = maxInt(width, x+svgMargin), maxInt(height, y+svgMargin)
+		}
+	}
+	for _, n := range f.nodes {
+		n.x, n.y = n.x-left, n.y-top
+	}
+	width, height = width-left, height-top
+
+	var b bytes.Buffer
+	svgOpen(&b, width, height)
+	for _, e := range f.edges {
+		if e.from == e.to {
+			x, y := e.from.x+e.from.w/2, e.from.y
+			b.WriteString(fmt.Sprintf("<path d=\"M%d,%d C%d,%d %d,%d %d,%d\" fill=\"none\" stroke=\"%s\"/>\n",
+				x, y-8, x+30, y-24, x+30, y+24, x, y+8, svgStroke))
+			if e.arrow {
+				svgArrow(&b, x+8, y+14, x, y+8)
+			}
+			continue
+		}
+
+		// an edge against the flow is bent aside, so it does not run over
+		// the edges and nodes between its ends
+		if e.to.rank <= e.from.rank {
+			cx, cy := e.bend()
+			x1, y1 := e.from.clip(cx, cy)
+			x2, y2 := e.to.clip(cx, cy)
+			b.WriteString(fmt.Sprintf("<path d=\"M%d,%d Q%d,%d %d,%d\" fill=\"none\" stroke=\"%s\"", x1, y1, cx, cy, x2, y2, svgStroke))
+			if e.dashed {
+				b.WriteString(" stroke-dasharray=\"4 3\"")
+			}
+			b.WriteString("/>\n")
+			if e.arrow {
+				svgArrow(&b, cx, cy, x2, y2)
+			}
+			continue
+		}
+
+		x1, y1 := e.from.clip(e.to.x, e.to.y)
+		x2, y2 := e.to.clip(e.from.x, e.from.y)
+		svgLine(&b, x1, y1, x2, y2, e.dashed)
+		if e.arrow {
+			svgArrow(&b, x1, y1, x2, y2)
+		}
+	}
+
+	for _, n := range f.nodes {
+		left, top := n.x-n.w/2, n.y-n.h/2
+		switch n.shape {
+		case '{':
+			b.WriteString(fmt.Sprintf("<polygon points=\"%d,%d %d,%d %d,%d %d,%d\" fill=\"#fff\" stroke=\"%s\"/>\n",
+				n.x, top, left+n.w, n.y, n.x, top+n.h, left, n.y, svgStroke))
+		case '(':
+			b.WriteString(fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" rx=\"%d\" fill=\"#fff\" stroke=\"%s\"/>\n",
+				left, top, n.w, n.h, n.h/2, svgStroke))
+		default:
+			b.WriteString(fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"#fff\" stroke=\"%s\"/>\n",
+				left, top, n.w, n.h, svgStroke))
+		}
+		svgText(&b, n.x, n.y, n.label)
+	}
+
+	for _, e := range f.edges {
+		if e.label == "" {
+			continue
+		}
+		if e.from == e.to {
+			svgLabel(&b, e.from.x+e.from.w/2+30+textWidth(e.label)/2, e.from.y, e.label)
+			continue
+		}
+		if e.to.rank <= e.from.rank {
+			cx, cy := e.bend()
+			svgLabel(&b, (e.from.x+2*cx+e.to.x)/4, (e.from.y+2*cy+e.to.y)/4, e.label)
+			continue
+		}
+		svgLabel(&b, (e.from.x+e.to.x)/2, (e.from.y+e.to.y)/2, e.label)
+	}
+	b.WriteString("</svg>")
+
+	return b.Bytes()
+}
+
+// noteSpan is the horizontal extent of the note st.
+func (st *step) noteSpan() (int, int) {
+	w := textWidth(st.text) + 20
+	switch st.side {
+	case "left of":
+		return st.from.x - 10 - w, st.from.x - 10
+	case "right of":
+		return st.from.x + 10, st.from.x + 10 + w
+	}
+
+	left, right := minInt(st.from.x, st.to.x), maxInt(st.from.x, st.to.x)
+	w = maxInt(w, right-left+40)
+	center := (left + right) / 2
+	return center - w/2, center + w/2
+}
+
+// layout places the lifelines of the actors so every message fits between
+// them, it returns the width of the diagram.
+func (s *sequence) layout() int {
+	index := make(map[*actor]int)
+	for i, a := range s.actors {
+		index[a] = i
+		a.w = maxInt(textWidth(a.label)+20, 80)
+	}
+
+	// gaps[i] is the distance between the lifelines of actors i-1 and i
+	gaps := make([]int, len(s.actors))
+	for i := 1; i < len(s.actors); i++ {
+		gaps[i] = (s.actors[i-1].w+s.actors[i].w)/2 + svgNodeGap
+	}
+	for _, st := range s.steps {
+		i, j := index[st.from], index[st.to]
+		if i > j {
+			i, j = j, i
+		}
+		need := textWidth(st.text) + 20
+		if st.side != "" {
+			if st.side != "right of" || j+1 >= len(gaps) {
+				continue
+			}
+			i, j, need = j, j+1, need+40
+		} else if i == j {
+			if j+1 >= len(gaps) {
+				continue
+			}
+			j, need = j+1, need+40
+		}
+
+		sum := 0
+		for k := i + 1; k <= j; k++ {
+			sum += gaps[k]
+		}
+		if sum < need {
+			gaps[j] += need - sum
+		}
+	}
+
+	x := 0
+	for i, a := range s.actors {
+		x += gaps[i]
+		a.x = x
+	}
+
+	left, right := 0, 0
+	for _, a := range s.actors {
+		left, right = minInt(left, a.x-a.w/2), maxInt(right, a.x+a.w/2)
+	}
+	for _, st := range s.steps {
+		switch {
+		case st.side != "":
+			l, r := st.noteSpan()
+			left, right = minInt(left, l), maxInt(right, r)
+		case st.from == st.to:
+			right = maxInt(right, st.from.x+40+textWidth(st.text)+10)
+		}
+	}
+
+	for _, a := range s.actors {
+		a.x += svgMargin - left
+	}
+
+	return right - left + 2*svgMargin
+}
+
+func (s *sequence) svg() []byte {
+	width := s.layout()
+
+	var body bytes.Buffer
+	y := svgMargin + svgBoxHeight + 20
+	for _, st := range s.steps {
+		switch {
+		case st.side != "":
+			l, r := st.noteSpan()
+			body.WriteString(fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"#fff8c4\" stroke=\"%s\"/>\n", l, y-4, r-l, 28, svgStroke))
+			svgText(&body, (l+r)/2, y+10, st.text)
+			y += 40
+
+		case st.from == st.to:
+			x := st.from.x
+			body.WriteString(fmt.Sprintf("<path d=\"M%d,%d H%d V%d H%d\" fill=\"none\" stroke=\"%s\"", x, y+8, x+30, y+28, x, svgStroke))
+			if st.dashed {
+				body.WriteString(" stroke-dasharray=\"4 3\"")
+			}
+			body.WriteString("/>\n")
+			if st.arrow {
+				svgArrow(&body, x+30, y+28, x, y+28)
+			}
+			body.WriteString(fmt.Sprintf("<text x=\"%d\" y=\"%d\" dominant-baseline=\"central\">", x+40, y+18))
+			body.Write(escapeHTML([]byte(st.text)))
+			body.WriteString("</text>\n")
+			y += 50
+
+		default:
+			x1, x2 := st.from.x, st.to.x
+			svgText(&body, (x1+x2)/2, y-4, st.text)
+			svgLine(&body, x1, y+8, x2, y+8, st.dashed)
+			if st.arrow {
+				svgArrow(&body, x1, y+8, x2, y+8)
+			}
+			y += 40
+		}
+	}
+	bottom := y
+
+	var b bytes.Buffer
+	svgOpen(&b, width, bottom+svgBoxHeight+svgMargin)
+	for _, a := range s.actors {
+		svgLine(&b, a.x, svgMargin+svgBoxHeight, a.x, bottom, true)
+	}
+	for _, top := range []int{svgMargin, bottom} {
+		for _, a := range s.actors {
+			b.WriteString(fmt.Sprintf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"#fff\" stroke=\"%s\"/>\n",
+				a.x-a.w/2, top, a.w, svgBoxHeight, svgStroke))
+			svgText(&b, a.x, top+svgBoxHeight/2, a.label)
+		}
+	}
+	b.Write(body.Bytes())
+	b.WriteString("</svg>")
+
+	return b.Bytes()
+}
@@ -0,0 +1,124 @@
+package markdown
+
+import (
+	"bytes"
+)
+
+var (
+	pipeSign    = []byte("|")
+	escapedPipe = []byte("\\|")
+)
+
+// isTableStart reports whether the line at index i is a table header, that
+// is followed by a delimiter row with the same number of cells.
+func (p *blockParser) isTableStart(i int) bool {
+	if i+1 >= len(p.lines) {
+		return false
+	}
+
+	header := p.lines[i].text
+	if !bytes.Contains(header, pipeSign) || indentWidth(leadingSpace(header)) >= 4 {
+		return false
+	}
+
+	delimiter := p.lines[i+1].text
+	if !reTableDelimiter.Match(delimiter) {
+		return false
+	}
+
+	return len(splitTableRow(header)) == len(splitTableRow(delimiter))
+}
+
+// parseTable consumes the header, the delimiter row and the body rows up to
+// a blank line or the start of another block.
+func (p *blockParser) parseTable() Node {
+	header := p.peek()
+	aligns := tableAligns(splitTableRow(p.lines[p.pos+1].text))
+	p.pos += 2
+
+	table := &Table{
+		Position: Position{Line: header.num, Column: header.col + len(leadingSpace(header.text))},
+		Aligns:   aligns,
+		Rows:     []*TableRow{newTableRow(header, aligns, true)},
+	}
+
+	for p.pos < len(p.lines) {
+		l := p.peek()
+		if isBlank(l.text) || p.interrupts(p.pos) {
+			break
+		}
+		table.Rows = append(table.Rows, newTableRow(l, aligns, false))
+		p.pos++
+	}
+
+	return table
+}
+
+// newTableRow builds a row with exactly one cell per column, missing cells
+// are empty and extra cells are dropped.
+func newTableRow(l line, aligns []Align, header bool) *TableRow {
+	pos := Position{Line: l.num, Column: l.col + len(leadingSpace(l.text))}
+	cells := splitTableRow(l.text)
+
+	row := &TableRow{Position: pos, Header: header}
+	for i, align := range aligns {
+		cell := &TableCell{Position: pos, Header: header, Align: align}
+		if i < len(cells) {
+			cell.Text = cells[i]
+		}
+		row.Cells = append(row.Cells, cell)
+	}
+
+	return row
+}
+
+// splitTableRow splits a table row into trimmed cells, the optional
+// leading and trailing pipes are dropped and `\|` stays in the cell as a
+// literal pipe.
+func splitTableRow(line []byte) [][]byte {
+	line = bytes.TrimSpace(line)
+	if bytes.HasPrefix(line, pipeSign) {
+		line = line[1:]
+	}
+	if bytes.HasSuffix(line, pipeSign) && !bytes.HasSuffix(line, escapedPipe) {
+		line = line[:len(line)-1]
+	}
+
+	var cells [][]byte
+	var start int
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '|':
+			cells = append(cells, tableCell(line[start:i]))
+			start = i + 1
+		}
+	}
+	cells = append(cells, tableCell(line[start:]))
+
+	return cells
+}
+
+func tableCell(input []byte) []byte {
+	cell := bytes.TrimSpace(input)
+	return bytes.Replace(cell, escapedPipe, pipeSign, -1)
+}
+
+func tableAligns(delimiters [][]byte) []Align {
+	aligns := make([]Align, len(delimiters))
+	for i, d := range delimiters {
+		left := bytes.HasPrefix(d, []byte(":"))
+		right := bytes.HasSuffix(d, []byte(":"))
+		switch {
+		case left && right:
+			aligns[i] = AlignCenter
+		case left:
+			aligns[i] = AlignLeft
+		case right:
+			aligns[i] = AlignRight
+		}
+	}
+
+	return aligns
+}
@@ -0,0 +1,88 @@
+package markdown
+
+import "strings"
+
+// RenderText walks the tree rooted at node and renders it to plain text,
+// blocks are separated by a blank line and markup is dropped. Raw HTML,
+// definitions and footnotes are not part of the text.
+func RenderText(node Node) []byte {
+	return []byte(strings.TrimSpace(blockText(node)))
+}
+
+func blockText(node Node) string {
+	switch n := node.(type) {
+	case *Document:
+		return blocksText(n.Blocks)
+
+	case *Paragraph:
+		return plainText(n.Inlines)
+
+	case *Heading:
+		return plainText(n.Inlines)
+
+	case *Image:
+		return string(n.Alt)
+
+	case *CodeBlock:
+		return string(n.Code)
+
+	case *MathBlock:
+		return string(n.TeX)
+
+	case *Quote:
+		return blocksText(n.Blocks)
+
+	case *Admonition:
+		return blocksText(append([]Node{&Text{Text: []byte(admonitionTitle(n))}}, n.Blocks...))
+
+	case *List:
+		items := make([]string, len(n.Items))
+		for i, item := range n.Items {
+			items[i] = blocksText(item.Blocks)
+		}
+		if n.Tight {
+			return strings.Join(items, "\n")
+		}
+		return strings.Join(items, "\n\n")
+
+	case *DefinitionList:
+		items := make([]string, len(n.Items))
+		for i, item := range n.Items {
+			items[i] = blockText(item)
+		}
+		return strings.Join(items, "\n")
+
+	case *DefinitionTerm:
+		return plainText(n.Inlines)
+
+	case *Definition:
+		return blocksText(n.Blocks)
+
+	case *Table:
+		rows := make([]string, len(n.Rows))
+		for i, row := range n.Rows {
+			cells := make([]string, len(row.Cells))
+			for j, cell := range row.Cells {
+				cells[j] = plainText(cell.Inlines)
+			}
+			rows[i] = strings.Join(cells, "\t")
+		}
+		return strings.Join(rows, "\n")
+
+	case *ThematicBreak, *Diagram, *HTMLBlock, *LinkDefinition, *FootnoteDefinition, *AbbreviationDefinition:
+		return ""
+	}
+
+	return plainText([]Node{node})
+}
+
+func blocksText(blocks []Node) string {
+	var texts []string
+	for _, block := range blocks {
+		if text := blockText(block); text != "" {
+			texts = append(texts, text)
+		}
+	}
+
+	return strings.Join(texts, "\n\n")
+}
@@ -0,0 +1,152 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var reHeadingID *regexp.Regexp
+
+func init() {
+	reHeadingID = regexp.MustCompile(`[ \t]*\{#([A-Za-z][\w:.-]*)\}[ \t]*$`)
+}
+
+// TOCEntry is one heading of the document outline, the headings of a
+// deeper level that follow it are its children.
+type TOCEntry struct {
+	Level    int
+	ID       string
+	Title    string
+	Children []*TOCEntry
+}
+
+// TableOfContents collects the headings of doc into a nested outline.
+func TableOfContents(doc *Document) []*TOCEntry {
+	var roots []*TOCEntry
+	var stack []*TOCEntry
+
+	Walk(doc, func(node Node) bool {
+		heading, ok := node.(*Heading)
+		if !ok {
+			return true
+		}
+
+		entry := &TOCEntry{
+			Level: heading.Level,
+			ID:    heading.ID,
+			Title: headingText(heading),
+		}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= entry.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, entry)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, entry)
+		}
+		stack = append(stack, entry)
+
+		return false
+	})
+
+	return roots
+}
+
+// splitHeadingIDs moves a trailing `{#id}` of the text of every heading
+// of doc into its ID.
+func splitHeadingIDs(doc *Document) {
+	Walk(doc, func(node Node) bool {
+		heading, ok := node.(*Heading)
+		if !ok {
+			return true
+		}
+
+		if ret := reHeadingID.FindSubmatchIndex(heading.Text); ret != nil {
+			heading.ID = string(heading.Text[ret[2]:ret[3]])
+			heading.Text = bytes.TrimRight(heading.Text[:ret[0]], " \t")
+		}
+
+		return false
+	})
+}
+
+// assignHeadingIDs gives every heading of doc a slug id which is unique
+// in the document, the n-th duplicate of a slug gets the suffix -n. An id
+// given by `{#id}` is kept, unless an earlier heading has the same one.
+func assignHeadingIDs(doc *Document) {
+	used := make(map[string]int)
+	preset := make(map[*Heading]bool)
+	Walk(doc, func(node Node) bool {
+		if heading, ok := node.(*Heading); ok && heading.ID != "" {
+			if _, exist := used[heading.ID]; !exist {
+				used[heading.ID] = 1
+				preset[heading] = true
+			}
+		}
+		return true
+	})
+
+	Walk(doc, func(node Node) bool {
+		heading, ok := node.(*Heading)
+		if !ok {
+			return true
+		}
+		if preset[heading] {
+			return false
+		}
+
+		slug := heading.ID
+		if slug == "" {
+			slug = slugify(headingText(heading))
+		}
+		id := slug
+		for count := used[slug]; ; count++ {
+			if count > 0 {
+				id = fmt.Sprintf("%s-%d", slug, count)
+			}
+			if _, exist := used[id]; !exist {
+				used[slug] = count + 1
+				used[id] = 1
+				break
+			}
+		}
+		heading.ID = id
+
+		return false
+	})
+}
+
+// headingText is the plain text of the heading, without inline markup.
+func headingText(heading *Heading) string {
+	return plainText(heading.Inlines)
+}
+
+// slugify lowercases text and keeps its letters, including Han characters,
+// and digits, runs of spaces and dashes become a single dash.
+func slugify(text string) string {
+	var builder strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && builder.Len() > 0 {
+				builder.WriteByte('-')
+			}
+			dash = false
+			builder.WriteRune(r)
+
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			dash = true
+		}
+	}
+
+	if builder.Len() == 0 {
+		return "section"
+	}
+
+	return builder.String()
+}
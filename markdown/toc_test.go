@@ -0,0 +1,102 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	input := []string{
+		"Hello World",
+		"  Go 1.9 -- what's new?  ",
+		"中文 标题",
+		"snake_case and CamelCase",
+		"!!!",
+	}
+
+	output := []string{
+		"hello-world",
+		"go-19-whats-new",
+		"中文-标题",
+		"snake-case-and-camelcase",
+		"section",
+	}
+
+	for i, v := range input {
+		result := slugify(v)
+		if result != output[i] {
+			t.Fatalf("Slugify fail, [%s] vs [%s]", result, output[i])
+		}
+	}
+}
+
+func TestHeadingIDs(t *testing.T) {
+	input := []byte("# Intro\n\n## **Bold** `code`\n\n## Intro\n\n### Intro")
+	doc := Parse(input)
+
+	output := []string{"intro", "bold-code", "intro-1", "intro-2"}
+	for i, block := range doc.Blocks {
+		heading := block.(*Heading)
+		if heading.ID != output[i] {
+			t.Fatalf("HeadingIDs fail, [%s] vs [%s]", heading.ID, output[i])
+		}
+	}
+}
+
+func TestCustomHeadingIDs(t *testing.T) {
+	parser := NewParser()
+	parser.Enable(HeadingIDs)
+
+	input := []byte("# Intro\n\n## Getting started {#intro}\n\nSetext {#setext}\n---\n\n## Again {#intro}\n\n## No {#1bad}")
+	doc := parser.Parse(input)
+
+	output := []string{"intro-1", "intro", "setext", "intro-2", "no-1bad"}
+	for i, block := range doc.Blocks {
+		heading := block.(*Heading)
+		if heading.ID != output[i] {
+			t.Fatalf("CustomHeadingIDs fail, [%s] vs [%s]", heading.ID, output[i])
+		}
+	}
+
+	result := RenderHTML(doc.Blocks[1])
+	expect := []byte("\n<h2 id=\"intro\"> Getting started </h2>\n")
+	if !bytes.Equal(result, expect) {
+		t.Fatalf("CustomHeadingIDs fail, [%s] vs [%s]", string(result), string(expect))
+	}
+
+	if id := Parse([]byte("# Title {#x}")).Blocks[0].(*Heading).ID; id != "title-x" {
+		t.Fatalf("CustomHeadingIDs disabled fail, [%s] vs [%s]", id, "title-x")
+	}
+}
+
+func TestHeadingAnchors(t *testing.T) {
+	input := []byte("## 中文")
+	output := []byte("\n<h2 id=\"中文\"> 中文 <a class=\"anchor\" href=\"#中文\">#</a> </h2>\n")
+
+	result := RenderWithOptions(input, Options{HeadingAnchors: true})
+	if !bytes.Equal(result, output) {
+		t.Fatalf("HeadingAnchors fail, [%s] vs [%s]", string(result), string(output))
+	}
+}
+
+func TestTableOfContents(t *testing.T) {
+	input := []byte("## A\n\n### A.1\n\n#### A.1.1\n\n### A.2\n\n# B\n\n> ## quoted")
+	toc := TableOfContents(Parse(input))
+
+	if len(toc) != 2 || toc[0].Title != "A" || toc[1].Title != "B" {
+		t.Fatalf("TableOfContents fail, invalid roots %+v", toc)
+	}
+
+	a := toc[0]
+	if len(a.Children) != 2 || a.Children[0].ID != "a1" || a.Children[1].ID != "a2" {
+		t.Fatalf("TableOfContents fail, invalid children %+v", a.Children)
+	}
+	if len(a.Children[0].Children) != 1 || a.Children[0].Children[0].Level != 4 {
+		t.Fatalf("TableOfContents fail, invalid grandchildren %+v", a.Children[0].Children)
+	}
+
+	b := toc[1]
+	if len(b.Children) != 1 || b.Children[0].Title != "quoted" {
+		t.Fatalf("TableOfContents fail, invalid quoted heading %+v", b.Children)
+	}
+}
@@ -0,0 +1,121 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TypographyOptions enables smart typography on the text of the document:
+// straight quotes become curly ones, `--` and `---` become en and em
+// dashes, and `...` an ellipsis. Code, math, raw HTML and the text of
+// autolinks are left alone, as is full-width punctuation.
+type TypographyOptions struct {
+	// NoCJKSpacing turns off the thin space put between Han or kana
+	// characters and Latin letters or digits.
+	NoCJKSpacing bool
+}
+
+// thinSpace separates Han characters from Latin letters and digits.
+const thinSpace = "\u2009"
+
+// typography is the state of the typography pass within a block.
+type typography struct {
+	// prev is the last rune written, 0 at the start of a block
+	prev rune
+	// double reports whether a double quote is open
+	double bool
+	// off is set inside of autolinks
+	off bool
+}
+
+// resetTypography is called at the start of every block with inline
+// content.
+func (r *htmlRenderer) resetTypography() {
+	r.typo = typography{}
+}
+
+// writeText writes text escaped, with the typography pass if it is
+// enabled.
+func (r *htmlRenderer) writeText(text []byte) {
+	if r.opts.Typography == nil || r.typo.off {
+		r.writeEscaped(text)
+		return
+	}
+
+	for i := 0; i < len(text); {
+		c, size := utf8.DecodeRune(text[i:])
+		out := string(c)
+
+		switch {
+		case c == '.' && bytes.HasPrefix(text[i:], []byte("...")):
+			c, out, size = '…', "…", 3
+
+		case c == '-' && bytes.HasPrefix(text[i:], []byte("---")):
+			c, out, size = '—', "—", 3
+
+		case c == '-' && bytes.HasPrefix(text[i:], []byte("--")):
+			c, out, size = '–', "–", 2
+
+		case c == '"':
+			if opensQuote(r.typo.prev) || isCJK(r.typo.prev) && !r.typo.double {
+				c, out = '“', "“"
+				r.typo.double = true
+			} else {
+				c, out = '”', "”"
+				r.typo.double = false
+			}
+
+		case c == '\'':
+			if opensQuote(r.typo.prev) {
+				c, out = '‘', "‘"
+			} else {
+				c, out = '’', "’"
+			}
+
+		case c == '&' || c == '<' || c == '>':
+			out = string(escapeHTML([]byte(out)))
+		}
+
+		if !r.opts.Typography.NoCJKSpacing && needsSpace(r.typo.prev, c) {
+			r.buffer.WriteString(thinSpace)
+		}
+		r.buffer.WriteString(out)
+		r.typo.prev = c
+		i += size
+	}
+}
+
+// settle records the last rune of text written without the typography
+// pass, such as code, so spacing and quotes around it still work.
+func (r *htmlRenderer) settle(text []byte) {
+	if r.opts.Typography == nil || len(text) == 0 {
+		return
+	}
+
+	first, _ := utf8.DecodeRune(text)
+	if !r.opts.Typography.NoCJKSpacing && needsSpace(r.typo.prev, first) {
+		r.buffer.WriteString(thinSpace)
+	}
+	r.typo.prev, _ = utf8.DecodeLastRune(text)
+}
+
+// opensQuote reports whether a quote after prev opens a quotation.
+func opensQuote(prev rune) bool {
+	return prev == 0 || unicode.IsSpace(prev) || strings.ContainsRune("([{<-–—“‘（【《「『", prev)
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+func isLatin(r rune) bool {
+	return r < utf8.RuneSelf && isAlnum(byte(r))
+}
+
+// needsSpace reports whether a and b are a Han or kana character and a
+// Latin letter or digit, in either order.
+func needsSpace(a, b rune) bool {
+	return isCJK(a) && isLatin(b) || isLatin(a) && isCJK(b)
+}
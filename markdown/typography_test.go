@@ -0,0 +1,41 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTypography(t *testing.T) {
+	input := [][]byte{
+		[]byte(`"Hello," she said -- 'it's fine'... --- really`),
+		[]byte("在Go语言中，使用`go test`运行100个测试"),
+		[]byte("他说\"你好\"，然后离开了。——这是破折号……"),
+		[]byte("see https://a.com/x--y and <https://b.com/--z> or `a -- \"b\"`"),
+		[]byte("中文**English**中文 and $x$公式"),
+		[]byte("```\n\"quoted\" -- code\n```"),
+		[]byte("# \"Title\" -- 标题A"),
+	}
+
+	output := [][]byte{
+		[]byte("\n<p>“Hello,” she said – ‘it’s fine’… — really</p>\n"),
+		[]byte("\n<p>在\u2009Go\u2009语言中，使用\u2009<code>go test</code>\u2009运行\u2009100\u2009个测试</p>\n"),
+		[]byte("\n<p>他说“你好”，然后离开了。——这是破折号……</p>\n"),
+		[]byte("\n<p>see <a href=\"https://a.com/x--y\">https://a.com/x--y</a> and <a href=\"https://b.com/--z\">https://b.com/--z</a> or <code>a -- &quot;b&quot;</code></p>\n"),
+		[]byte("\n<p>中文<strong>\u2009English</strong>\u2009中文 and <span class=\"math inline\">\\(x\\)</span>\u2009公式</p>\n"),
+		[]byte("\n<pre>\n<code>\n&quot;quoted&quot; -- code\n</code>\n</pre>\n"),
+		[]byte("\n<h1 id=\"title-标题a\"> “Title” – 标题\u2009A </h1>\n"),
+	}
+
+	opts := Options{Typography: &TypographyOptions{}}
+	for i, v := range input {
+		result := RenderWithOptions(v, opts)
+		if !bytes.Equal(result, output[i]) {
+			t.Fatalf("Typography fail, [%s] vs [%s]", string(result), string(output[i]))
+		}
+	}
+
+	result := RenderWithOptions([]byte("用Go写\"代码\""), Options{Typography: &TypographyOptions{NoCJKSpacing: true}})
+	if string(result) != "\n<p>用Go写“代码”</p>\n" {
+		t.Fatalf("Typography fail, [%s]", string(result))
+	}
+}
@@ -4,18 +4,34 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/cvley/cvblog"
 )
 
 var (
-	dir string
+	dir       string
+	drafts    bool
+	permalink string
+	timezone  string
+	site      string
+	title     string
+	author    string
+	mtime     bool
 )
 
 func init() {
 	flag.StringVar(&dir, "dir", "", "markdown file directory")
+	flag.BoolVar(&drafts, "drafts", false, "include drafts for a local preview")
+	flag.StringVar(&permalink, "permalink", cvblog.Permalink, "URL pattern of posts, such as /:year/:month/:slug/")
+	flag.StringVar(&timezone, "timezone", "UTC", "time zone of the dates without an offset, such as Asia/Shanghai")
+	flag.StringVar(&site, "site", "", "base URL of the site for the feed and the sitemap")
+	flag.StringVar(&title, "title", "", "title of the site in the feed, the host of -site by default")
+	flag.StringVar(&author, "author", "", "author of the feed, the title by default")
+	flag.BoolVar(&mtime, "mtime", false, "use the modification time of files as the updated time of posts")
 }
 
 func main() {
@@ -31,25 +47,56 @@ func main() {
 		return
 	}
 
-	posts := []*cvblog.Article{}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	cvblog.Location = location
+
+	names := []string{}
+	inputs := [][]byte{}
+	modified := []time.Time{}
 	for _, file := range files {
-		f, err := os.Open(file)
+		b, err := ioutil.ReadFile(file)
 		if err != nil {
 			fmt.Println(err)
 			continue
 		}
-
-		b, err := ioutil.ReadAll(f)
+		info, err := os.Stat(file)
 		if err != nil {
 			fmt.Println(err)
 			continue
 		}
-		post := cvblog.NewArticle(b)
-		posts = append(posts, post)
+		names = append(names, file)
+		inputs = append(inputs, b)
+		modified = append(modified, info.ModTime())
+	}
+
+	cvblog.Permalink = permalink
+	posts, err := cvblog.BuildArticles(names, inputs, cvblog.DefaultShortcodes)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	for i, post := range posts {
+		if mtime {
+			post.SetModified(modified[i])
+		}
+		for _, w := range post.Warnings {
+			fmt.Printf("%s:%s\n", names[i], w)
+		}
 	}
 
 	render := cvblog.NewRender(posts, "just about")
 	render.SetOutputDir("html")
+	render.SetDrafts(drafts)
+	if title == "" {
+		if u, err := url.Parse(site); err == nil {
+			title = u.Host
+		}
+	}
+	render.SetSite(title, site, author)
 
 	render.ToIndex()
 	render.ToPosts()
@@ -57,4 +104,8 @@ func main() {
 	render.ToArchive()
 	render.ToCategory()
 	render.ToTags()
+	if site != "" {
+		render.ToFeed()
+		render.ToSitemap()
+	}
 }
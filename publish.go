@@ -0,0 +1,67 @@
+package cvblog
+
+import (
+	"strings"
+	"time"
+)
+
+// State is the publishing state of an article.
+type State string
+
+const (
+	// StateDraft is not rendered, unless drafts are included for preview.
+	StateDraft State = "draft"
+	// StatePublished is rendered and listed.
+	StatePublished State = "published"
+	// StateScheduled is a published article dated in the future, it is
+	// rendered by the first build after its date.
+	StateScheduled State = "scheduled"
+	// StatePrivate and StateUnlisted are rendered at their URL but are
+	// left out of the listings.
+	StatePrivate  State = "private"
+	StateUnlisted State = "unlisted"
+)
+
+var states = map[State]bool{
+	StateDraft:     true,
+	StatePublished: true,
+	StateScheduled: true,
+	StatePrivate:   true,
+	StateUnlisted:  true,
+}
+
+// status is the state declared by the Status or Draft of the article, it
+// is published if neither is given.
+func (a *Article) status() State {
+	if a.Draft {
+		return StateDraft
+	}
+
+	status := State(strings.ToLower(strings.TrimSpace(a.Status)))
+	if status == "" {
+		return StatePublished
+	}
+
+	return status
+}
+
+// StateAt is the state of the article at now, a published article dated
+// after now is scheduled and a scheduled one dated before is published.
+func (a *Article) StateAt(now time.Time) State {
+	status := a.status()
+	if status != StatePublished && status != StateScheduled {
+		return status
+	}
+	if a.Time.After(now) {
+		return StateScheduled
+	}
+
+	return StatePublished
+}
+
+// Indexed reports whether the page of the article may be indexed by search
+// engines, which private, unlisted and draft articles may not.
+func (a *Article) Indexed() bool {
+	status := a.status()
+	return status == StatePublished || status == StateScheduled
+}
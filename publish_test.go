@@ -0,0 +1,95 @@
+package cvblog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArticleState(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	input := []string{
+		"Date: 2012-10-25 12:22\nTitle: a\nURL: a\n\nbody",
+		"Date: 2012-10-25 12:22\nTitle: a\nStatus: Draft\nURL: a\n\nbody",
+		"---\ntitle: a\ndate: 2012-10-25\ndraft: true\nslug: a\n---\nbody",
+		"Date: 2099-10-25 12:22\nTitle: a\nURL: a\n\nbody",
+		"Date: 2012-10-25 12:22\nTitle: a\nStatus: scheduled\nURL: a\n\nbody",
+		"Date: 2099-10-25 12:22\nTitle: a\nStatus: published\nURL: a\n\nbody",
+		"Date: 2012-10-25 12:22\nTitle: a\nStatus: private\nURL: a\n\nbody",
+		"Date: 2012-10-25 12:22\nTitle: a\nStatus: unlisted\nURL: a\n\nbody",
+	}
+
+	output := []State{
+		StatePublished,
+		StateDraft,
+		StateDraft,
+		StateScheduled,
+		StatePublished,
+		StateScheduled,
+		StatePrivate,
+		StateUnlisted,
+	}
+
+	for i, v := range input {
+		paper := NewArticle([]byte(v))
+		if len(paper.Warnings) > 0 {
+			t.Fatalf("article state warnings fail, %v", paper.Warnings)
+		}
+		if state := paper.StateAt(now); state != output[i] {
+			t.Fatalf("article state fail, [%s] vs [%s]", state, output[i])
+		}
+	}
+
+	_, err := ParseArticle("a.md", []byte("Date: 2012-10-25 12:22\nTitle: a\nStatus: secret\nURL: a\n\nbody"))
+	if err == nil || err.Error() != "a.md:3: status: unknown status secret" {
+		t.Fatalf("article state error fail, %v", err)
+	}
+}
+
+func TestRenderStates(t *testing.T) {
+	input := []string{
+		"Date: 2012-10-25 12:22\nTitle: published\nTags: go\nURL: published\n\nbody",
+		"Date: 2012-10-25 12:22\nTitle: draft\nTags: draft\nStatus: draft\nURL: draft\n\nbody",
+		"Date: 2999-10-25 12:22\nTitle: scheduled\nURL: scheduled\n\nbody",
+		"Date: 2012-10-25 12:22\nTitle: private\nStatus: private\nURL: private\n\nbody",
+		"Date: 2012-10-25 12:22\nTitle: unlisted\nCategory: hidden\nStatus: unlisted\nURL: unlisted\n\nbody",
+	}
+
+	articles := make([]*Article, len(input))
+	for i, v := range input {
+		articles[i] = NewArticle([]byte(v))
+	}
+
+	urls := func(articles []*Article) string {
+		s := ""
+		for _, a := range articles {
+			s += a.URL + " "
+		}
+		return s
+	}
+
+	render := NewRender(articles, "")
+	if s := urls(render.posts); s != "published.html " {
+		t.Fatalf("render posts fail, [%s]", s)
+	}
+	if s := urls(render.pages); s != "published.html private.html unlisted.html " {
+		t.Fatalf("render pages fail, [%s]", s)
+	}
+	if len(render.categoryCount) != 1 || len(render.tagCount) != 1 || render.tagCount[0].Tag != "go" {
+		t.Fatalf("render listings fail, %v %v", render.categoryCount, render.tagCount)
+	}
+
+	render.SetDrafts(true)
+	if s := urls(render.posts); s != "published.html draft.html " {
+		t.Fatalf("render drafts fail, [%s]", s)
+	}
+	if s := urls(render.pages); s != "published.html draft.html private.html unlisted.html " {
+		t.Fatalf("render draft pages fail, [%s]", s)
+	}
+	if len(render.tagCount) != 2 {
+		t.Fatalf("render draft tags fail, %v", render.tagCount)
+	}
+
+	if articles[0].Indexed() != true || articles[3].Indexed() != false {
+		t.Fatalf("article indexed fail")
+	}
+}
@@ -3,6 +3,9 @@ package cvblog
 import (
 	"html/template"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 var (
@@ -28,11 +31,22 @@ type TagCount struct {
 }
 
 type Render struct {
+	// articles are all the articles given to NewRender
+	articles []*Article
+	// posts are the articles which are listed, pages are the ones which
+	// are rendered to their URL
 	posts         []*Article
+	pages         []*Article
 	categoryCount []*CategoryCount
 	tagCount      []*TagCount
 	about         string
 	outputDir     string
+	drafts        bool
+	now           time.Time
+	// title, baseURL and author are the site of the feed and the sitemap
+	title   string
+	baseURL string
+	author  string
 }
 
 func init() {
@@ -45,13 +59,53 @@ func init() {
 	baseTmpl = template.Must(template.New("base.html").ParseFiles("./templates/base.html"))
 }
 
+// NewRender renders the articles by their state at the time it is called:
+// published ones are listed, private and unlisted ones are only rendered to
+// their URL, drafts and scheduled ones are left out.
 func NewRender(posts []*Article, about string) *Render {
+	r := &Render{
+		articles:  posts,
+		about:     about,
+		outputDir: "",
+		now:       time.Now(),
+	}
+	r.classify()
+
+	return r
+}
+
+// SetDrafts includes the drafts in the site as if they were published, for
+// a local preview.
+func (r *Render) SetDrafts(include bool) {
+	r.drafts = include
+	r.classify()
+}
+
+// classify sorts the articles into the listed posts and the rendered pages
+// by their state, and counts the categories and tags of the posts.
+func (r *Render) classify() {
+	r.posts, r.pages = []*Article{}, []*Article{}
+	for _, v := range r.articles {
+		switch v.StateAt(r.now) {
+		case StatePublished:
+			r.posts = append(r.posts, v)
+			r.pages = append(r.pages, v)
+		case StatePrivate, StateUnlisted:
+			r.pages = append(r.pages, v)
+		case StateDraft:
+			if r.drafts {
+				r.posts = append(r.posts, v)
+				r.pages = append(r.pages, v)
+			}
+		}
+	}
+
 	catCount := make(map[string]int)
 	catLinks := make(map[string][]*Article)
 	tagCount := make(map[string]int)
 	tagLinks := make(map[string][]*Article)
 
-	for _, v := range posts {
+	for _, v := range r.posts {
 		if count, exist := catCount[v.Category]; exist {
 			catCount[v.Category] = count + 1
 			catLinks[v.Category] = append(catLinks[v.Category], v)
@@ -91,29 +145,34 @@ func NewRender(posts []*Article, about string) *Render {
 		tagResult = append(tagResult, tag)
 	}
 
-	return &Render{
-		posts:         posts,
-		categoryCount: catResult,
-		tagCount:      tagResult,
-		about:         about,
-		outputDir:     "",
-	}
+	r.categoryCount = catResult
+	r.tagCount = tagResult
 }
 
 func (r *Render) SetOutputDir(dir string) {
 	r.outputDir = dir
 }
 
+// outputFile creates the file name in the output directory with the
+// directories above it, a name which ends with `/` is its index.html.
 func (r *Render) outputFile(name string) (*os.File, error) {
 	if r.outputDir == "" {
 		return os.Stdout, nil
 	}
 
-	return os.Create(r.outputDir + "/" + name)
+	if strings.HasSuffix(name, "/") {
+		name += "index.html"
+	}
+	path := filepath.Join(r.outputDir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	return os.Create(path)
 }
 
 func (r *Render) ToPosts() error {
-	for _, v := range r.posts {
+	for _, v := range r.pages {
 		f, err := r.outputFile(v.URL)
 		if err != nil {
 			return err
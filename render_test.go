@@ -12,6 +12,7 @@ var (
 func init() {
 	input := []string{
 		"Date: 2012-10-25 12:22\nTitle: 我是文章的标题2\nCategory: Test\nTags: 标签1, 标签2\nStatus: draft\nURL: this-is-my-first-post\n\n然后开始写正文...",
+		"Date: 2012-10-26 12:22\nTitle: 我是文章的标题3\nTags: 标签1\nURL: this-is-my-second-post\n\n## 第一节\n\n### 小节\n\n## 第二节",
 	}
 
 	posts = make([]*Article, len(input))
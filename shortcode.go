@@ -0,0 +1,391 @@
+package cvblog
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cvley/cvblog/markdown"
+)
+
+// Shortcode expands a `{{< name args >}}` call of an article body to HTML.
+type Shortcode func(call *ShortcodeCall) (template.HTML, error)
+
+// ShortcodeCall is a shortcode in the body, `key="value"` arguments are
+// kept in Params and the others in Args.
+type ShortcodeCall struct {
+	Name   string
+	Args   []string
+	Params map[string]string
+
+	posts map[string]*Article
+}
+
+// Get is the named argument key, or "" if it is not given.
+func (c *ShortcodeCall) Get(key string) string {
+	return c.Params[key]
+}
+
+// Post is the article whose URL is slug, with or without the ".html"
+// suffix, or nil.
+func (c *ShortcodeCall) Post(slug string) *Article {
+	return c.posts[strings.TrimSuffix(strings.Trim(slug, "/"), ".html")]
+}
+
+// Shortcodes is a registry of shortcodes.
+type Shortcodes struct {
+	funcs map[string]Shortcode
+}
+
+// DefaultShortcodes is used by NewArticle, it holds the built-in shortcodes
+// and the templates of ./templates/shortcodes.
+var DefaultShortcodes = NewShortcodes()
+
+func init() {
+	if err := DefaultShortcodes.LoadTemplates("./templates/shortcodes"); err != nil {
+		panic(err)
+	}
+}
+
+// NewShortcodes returns a registry of the built-in shortcodes: figure,
+// gist, video and post.
+func NewShortcodes() *Shortcodes {
+	s := &Shortcodes{funcs: make(map[string]Shortcode)}
+	s.Register("figure", figureShortcode)
+	s.Register("gist", gistShortcode)
+	s.Register("video", videoShortcode)
+	s.Register("post", postShortcode)
+
+	return s
+}
+
+// Register adds fn as the shortcode name, replacing the one of the same
+// name.
+func (s *Shortcodes) Register(name string, fn Shortcode) {
+	s.funcs[name] = fn
+}
+
+// LoadTemplates registers every `name.html` file of dir as the shortcode
+// name, the template is executed with the *ShortcodeCall. A missing dir
+// is not an error.
+func (s *Shortcodes) LoadTemplates(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".html")
+		tmpl, err := template.New(filepath.Base(file)).ParseFiles(file)
+		if err != nil {
+			return err
+		}
+		s.Register(name, func(call *ShortcodeCall) (template.HTML, error) {
+			var buffer bytes.Buffer
+			if err := tmpl.Execute(&buffer, call); err != nil {
+				return "", err
+			}
+			return template.HTML(buffer.String()), nil
+		})
+	}
+
+	return nil
+}
+
+func (s *Shortcodes) expand(call *ShortcodeCall) (template.HTML, error) {
+	fn, ok := s.funcs[call.Name]
+	if !ok {
+		return "", fmt.Errorf("unknown shortcode %q", call.Name)
+	}
+
+	return fn(call)
+}
+
+func figureShortcode(call *ShortcodeCall) (template.HTML, error) {
+	src := call.Get("src")
+	if src == "" {
+		return "", fmt.Errorf("figure needs a src")
+	}
+	if !markdown.SafeURL(src, true) {
+		return "", fmt.Errorf("figure src %q is not safe", src)
+	}
+	link := call.Get("link")
+	if link != "" && !markdown.SafeURL(link, false) {
+		return "", fmt.Errorf("figure link %q is not safe", link)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("<figure>")
+	if link != "" {
+		buffer.WriteString(fmt.Sprintf("<a href=\"%s\">", template.HTMLEscapeString(link)))
+	}
+	alt := call.Get("alt")
+	if alt == "" {
+		alt = call.Get("caption")
+	}
+	buffer.WriteString(fmt.Sprintf("<img src=\"%s\" alt=\"%s\"", template.HTMLEscapeString(src), template.HTMLEscapeString(alt)))
+	if title := call.Get("title"); title != "" {
+		buffer.WriteString(fmt.Sprintf(" title=\"%s\"", template.HTMLEscapeString(title)))
+	}
+	buffer.WriteString(">")
+	if link != "" {
+		buffer.WriteString("</a>")
+	}
+	if caption := call.Get("caption"); caption != "" {
+		buffer.WriteString(fmt.Sprintf("<figcaption>%s</figcaption>", template.HTMLEscapeString(caption)))
+	}
+	buffer.WriteString("</figure>")
+
+	return template.HTML(buffer.String()), nil
+}
+
+func gistShortcode(call *ShortcodeCall) (template.HTML, error) {
+	if len(call.Args) < 2 {
+		return "", fmt.Errorf("gist needs a user and an id")
+	}
+
+	src := fmt.Sprintf("https://gist.github.com/%s/%s.js", call.Args[0], call.Args[1])
+	if len(call.Args) > 2 {
+		src += "?file=" + call.Args[2]
+	}
+
+	return template.HTML(fmt.Sprintf("<script src=\"%s\"></script>", template.HTMLEscapeString(src))), nil
+}
+
+func videoShortcode(call *ShortcodeCall) (template.HTML, error) {
+	src := call.Get("src")
+	if src == "" && len(call.Args) > 0 {
+		src = call.Args[0]
+	}
+	if src == "" {
+		return "", fmt.Errorf("video needs a src")
+	}
+	if !markdown.SafeURL(src, false) {
+		return "", fmt.Errorf("video src %q is not safe", src)
+	}
+	poster := call.Get("poster")
+	if poster != "" && !markdown.SafeURL(poster, true) {
+		return "", fmt.Errorf("video poster %q is not safe", poster)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString(fmt.Sprintf("<video controls preload=\"metadata\" src=\"%s\"", template.HTMLEscapeString(src)))
+	if poster != "" {
+		buffer.WriteString(fmt.Sprintf(" poster=\"%s\"", template.HTMLEscapeString(poster)))
+	}
+	buffer.WriteString("></video>")
+
+	return template.HTML(buffer.String()), nil
+}
+
+func postShortcode(call *ShortcodeCall) (template.HTML, error) {
+	if len(call.Args) == 0 {
+		return "", fmt.Errorf("post needs a slug")
+	}
+
+	post := call.Post(call.Args[0])
+	if post == nil {
+		return "", fmt.Errorf("unknown post %q", call.Args[0])
+	}
+	// a link to a draft or a scheduled post would be broken, and its title
+	// not yet public
+	switch post.StateAt(time.Now()) {
+	case StateDraft:
+		return "", fmt.Errorf("post %q is a draft", call.Args[0])
+	case StateScheduled:
+		return "", fmt.Errorf("post %q is scheduled for %s", call.Args[0], post.Time.Format("2006-01-02"))
+	}
+
+	return template.HTML(fmt.Sprintf("<a href=\"/%s\">%s</a>", template.HTMLEscapeString(post.URL), post.Title)), nil
+}
+
+// parseShortcode parses the call at the start of input, it returns the
+// call, its length and whether it is escaped as `{{</* name */>}}`, which
+// is written as it is without the comment markers.
+func parseShortcode(input []byte) (*ShortcodeCall, int, bool) {
+	if !bytes.HasPrefix(input, []byte("{{<")) {
+		return nil, 0, false
+	}
+
+	i := skipBlank(input, 3)
+	escaped := bytes.HasPrefix(input[i:], []byte("/*"))
+	if escaped {
+		i += 2
+	}
+
+	call := &ShortcodeCall{Params: make(map[string]string)}
+	for {
+		i = skipBlank(input, i)
+		if escaped && bytes.HasPrefix(input[i:], []byte("*/")) {
+			i = skipBlank(input, i+2)
+			if !bytes.HasPrefix(input[i:], []byte(">}}")) {
+				return nil, 0, false
+			}
+			break
+		}
+		if !escaped && bytes.HasPrefix(input[i:], []byte(">}}")) {
+			break
+		}
+		if i >= len(input) {
+			return nil, 0, false
+		}
+
+		key, value, n := shortcodeArg(input[i:])
+		if n == 0 {
+			return nil, 0, false
+		}
+		i += n
+
+		switch {
+		case call.Name == "":
+			call.Name = value
+		case key != "":
+			call.Params[key] = value
+		default:
+			call.Args = append(call.Args, value)
+		}
+	}
+
+	if call.Name == "" {
+		return nil, 0, false
+	}
+
+	return call, i + 3, escaped
+}
+
+// shortcodeArg parses `value`, `"value"` or `key=value` at the start of
+// input.
+func shortcodeArg(input []byte) (string, string, int) {
+	key := ""
+	i := 0
+	for i < len(input) && (isWordByte(input[i]) || input[i] == '-') {
+		i++
+	}
+	if i > 0 && i < len(input) && input[i] == '=' {
+		key = string(input[:i])
+		i++
+	} else {
+		i = 0
+	}
+
+	if i < len(input) && input[i] == '"' {
+		end := bytes.IndexByte(input[i+1:], '"')
+		if end < 0 {
+			return "", "", 0
+		}
+		return key, string(input[i+1 : i+1+end]), i + end + 2
+	}
+
+	start := i
+	for i < len(input) && input[i] != ' ' && input[i] != '\t' && input[i] != '\n' &&
+		!bytes.HasPrefix(input[i:], []byte(">}}")) && !bytes.HasPrefix(input[i:], []byte("*/")) {
+		i++
+	}
+	if i == start {
+		return "", "", 0
+	}
+
+	return key, string(input[start:i]), i
+}
+
+func skipBlank(input []byte, i int) int {
+	for i < len(input) && (input[i] == ' ' || input[i] == '\t' || input[i] == '\n') {
+		i++
+	}
+
+	return i
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// shortcodeNode is an expanded shortcode, Block is set for a call on a line
+// of its own, which is not wrapped into a paragraph.
+type shortcodeNode struct {
+	markdown.Position
+	HTML  template.HTML
+	Block bool
+}
+
+func (n *shortcodeNode) Children() []markdown.Node { return nil }
+
+// shortcodeExpander expands the shortcodes of an article, calls which fail
+// are kept as text and reported in errs.
+type shortcodeExpander struct {
+	shortcodes *Shortcodes
+	posts      map[string]*Article
+	errs       []markdown.Warning
+}
+
+func (e *shortcodeExpander) expand(call *ShortcodeCall) (template.HTML, error) {
+	call.posts = e.posts
+	return e.shortcodes.expand(call)
+}
+
+// shortcodeBlocks parses a call on a line of its own as a block, a call
+// which fails is left to the paragraph, where shortcodeInlines reports it.
+type shortcodeBlocks struct {
+	*shortcodeExpander
+}
+
+func (s shortcodeBlocks) Open(line []byte) bool {
+	text := bytes.TrimSpace(line)
+	_, n, escaped := parseShortcode(text)
+	return n > 0 && n == len(text) && !escaped
+}
+
+func (s shortcodeBlocks) Parse(c *markdown.BlockContext) markdown.Node {
+	pos := c.Position()
+	call, _, _ := parseShortcode(bytes.TrimSpace(c.Line()))
+	html, err := s.expand(call)
+	if err != nil {
+		return nil
+	}
+	c.Advance()
+
+	return &shortcodeNode{Position: pos, HTML: html, Block: true}
+}
+
+type shortcodeInlines struct {
+	*shortcodeExpander
+}
+
+func (s shortcodeInlines) Triggers() []byte {
+	return []byte("{")
+}
+
+func (s shortcodeInlines) Parse(c *markdown.InlineContext) (markdown.Node, int) {
+	input := c.Input()
+	call, n, escaped := parseShortcode(input)
+	if n == 0 {
+		return nil, 0
+	}
+
+	// an escaped call is source code, which typography must not change
+	if escaped {
+		text := bytes.Replace(input[:n], []byte("/*"), nil, 1)
+		text = bytes.Replace(text, []byte("*/"), nil, 1)
+		return &shortcodeNode{Position: c.Position(), HTML: template.HTML(template.HTMLEscapeString(string(text)))}, n
+	}
+
+	html, err := s.expand(call)
+	if err != nil {
+		s.errs = append(s.errs, markdown.Warning{Position: c.Position(), Message: err.Error()})
+		return &markdown.Text{Position: c.Position(), Text: input[:n]}, n
+	}
+
+	return &shortcodeNode{Position: c.Position(), HTML: html}, n
+}
+
+func renderShortcode(w *markdown.HTMLWriter, node markdown.Node) {
+	n := node.(*shortcodeNode)
+	if n.Block {
+		w.WriteString("\n" + string(n.HTML) + "\n")
+		return
+	}
+	w.WriteString(string(n.HTML))
+}
@@ -0,0 +1,143 @@
+package cvblog
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseShortcode(t *testing.T) {
+	input := []string{
+		`{{< figure src="/a.png" caption="A cat" >}}`,
+		`{{<gist cvley 1234 main.go>}} tail`,
+		`{{</* video src */>}}`,
+		`{{< figure src="/a.png" `,
+	}
+
+	output := []string{
+		`figure [] map[caption:A cat src:/a.png] 43 false`,
+		`gist [cvley 1234 main.go] map[] 29 false`,
+		`video [src] map[] 21 true`,
+		`<nil>`,
+	}
+
+	for i, v := range input {
+		call, n, escaped := parseShortcode([]byte(v))
+		result := "<nil>"
+		if call != nil {
+			result = fmt.Sprintf("%s %v %v %d %v", call.Name, call.Args, call.Params, n, escaped)
+		}
+		if result != output[i] {
+			t.Fatalf("parse shortcode fail, [%s] vs [%s]", result, output[i])
+		}
+	}
+}
+
+func TestShortcodes(t *testing.T) {
+	input := [][]byte{
+		[]byte("Date: 2012-10-25 12:22\nTitle: A & B\nURL: first\n\nintro\n{{< figure src=\"/a.png\" caption=\"A cat\" >}}\n\n{{< video /v.mp4 >}}"),
+		[]byte("Date: 2012-10-26 12:22\nTitle: 第二篇\nURL: second\n\nsee {{< post \"first\" >}}, run `{{< gist a b >}}` and write {{</* post \"x\" */>}}"),
+	}
+
+	output := []string{
+		"\n<p>intro</p>\n\n<figure><img src=\"/a.png\" alt=\"A cat\"><figcaption>A cat</figcaption></figure>\n\n<video controls preload=\"metadata\" src=\"/v.mp4\"></video>\n",
+		"\n<p>see <a href=\"/first.html\">A &amp; B</a>, run <code>{{&lt; gist a b &gt;}}</code> and write {{&lt; post &#34;x&#34; &gt;}}</p>\n",
+	}
+
+	articles, err := BuildArticles([]string{"first.md", "second.md"}, input, NewShortcodes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, a := range articles {
+		if string(a.Body) != output[i] {
+			t.Fatalf("shortcode fail, [%s] vs [%s]", a.Body, output[i])
+		}
+	}
+}
+
+func TestUnsafeShortcodes(t *testing.T) {
+	input := []string{
+		`{{< figure src="javascript:alert(1)" >}}`,
+		`{{< figure src="/a.png" link="javascript:alert(2)" >}}`,
+		`{{< figure src="/a.png" link="data:text/html,x" >}}`,
+		`{{< video src="JavaScript:alert(3)" >}}`,
+		`{{< video src="data:video/mp4;base64,AAAA" >}}`,
+		`{{< video src="/v.mp4" poster="data:text/html,x" >}}`,
+	}
+
+	for _, v := range input {
+		paper := NewArticle([]byte("Date: 2012-10-25 12:22\nTitle: 危险\nURL: unsafe\n\n" + v))
+		if len(paper.Warnings) != 1 || !strings.Contains(paper.Warnings[0].Message, "is not safe") ||
+			strings.Contains(string(paper.Body), "<figure") || strings.Contains(string(paper.Body), "<video") {
+			t.Fatalf("unsafe shortcode fail, [%s] %v %s", v, paper.Warnings, paper.Body)
+		}
+	}
+
+	output := `<video controls preload="metadata" src="/v.mp4" poster="data:image/png;base64,AAAA"></video>`
+	paper := NewArticle([]byte("Date: 2012-10-25 12:22\nTitle: 海报\nURL: poster\n\n{{< video src=\"/v.mp4\" poster=\"data:image/png;base64,AAAA\" >}}"))
+	if len(paper.Warnings) > 0 || !strings.Contains(string(paper.Body), output) {
+		t.Fatalf("image poster fail, %v [%s] vs [%s]", paper.Warnings, paper.Body, output)
+	}
+}
+
+func TestShortcodeErrors(t *testing.T) {
+	input := [][]byte{
+		[]byte("Date: 2012-10-25 12:22\nTitle: 错误\nURL: broken\n\n{{< tweet 1 >}}\n\nsee {{< post \"none\" >}}"),
+	}
+
+	_, err := BuildArticles([]string{"broken.md"}, input, NewShortcodes())
+	if err == nil || !strings.Contains(err.Error(), `broken.md:5:1: unknown shortcode "tweet"`) ||
+		!strings.Contains(err.Error(), `broken.md:7:5: unknown post "none"`) {
+		t.Fatalf("shortcode error fail, %v", err)
+	}
+
+	paper := NewArticle(input[0])
+	if len(paper.Warnings) != 2 || !strings.Contains(string(paper.Body), "{{&lt; tweet 1 &gt;}}") {
+		t.Fatalf("shortcode warning fail, %v %s", paper.Warnings, paper.Body)
+	}
+}
+
+func TestPostShortcodeStates(t *testing.T) {
+	input := [][]byte{
+		[]byte("Date: 2012-10-25 12:22\nTitle: 草稿\nStatus: draft\nURL: wip\n\nbody"),
+		[]byte("Date: 2099-10-25 12:22\nTitle: 将来\nURL: later\n\nbody"),
+		[]byte("Date: 2012-10-25 12:22\nTitle: 私密\nStatus: private\nURL: mine\n\nbody"),
+		[]byte("Date: 2012-10-26 12:22\nTitle: 链接\nURL: links\n\n{{< post \"wip\" >}} {{< post \"later\" >}} {{< post \"mine\" >}}"),
+	}
+
+	articles, err := BuildArticles([]string{"wip.md", "later.md", "mine.md", "links.md"}, input, NewShortcodes())
+	output := "links.md:5:1: post \"wip\" is a draft\nlinks.md:5:20: post \"later\" is scheduled for 2099-10-25"
+	if err == nil || err.Error() != output {
+		t.Fatalf("post shortcode state fail, [%v] vs [%s]", err, output)
+	}
+	if body := string(articles[3].Body); strings.Contains(body, "草稿") || strings.Contains(body, "将来") ||
+		!strings.Contains(body, `<a href="/mine.html">私密</a>`) {
+		t.Fatalf("post shortcode body fail, [%s]", body)
+	}
+}
+
+func TestShortcodeTemplates(t *testing.T) {
+	dir, err := os.MkdirTemp("", "shortcodes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmpl := `<span class="{{.Get "class"}}">{{index .Args 0}}</span>`
+	if err := os.WriteFile(filepath.Join(dir, "badge.html"), []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	shortcodes := NewShortcodes()
+	if err := shortcodes.LoadTemplates(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	html, err := shortcodes.expand(&ShortcodeCall{Name: "badge", Args: []string{"<new>"}, Params: map[string]string{"class": "hot"}})
+	if err != nil || html != template.HTML(`<span class="hot">&lt;new&gt;</span>`) {
+		t.Fatalf("shortcode template fail, %s %v", html, err)
+	}
+}
@@ -0,0 +1,89 @@
+package cvblog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Permalink is the pattern of the URL of every article, `:year`, `:month`
+// and `:day` are replaced by its date and `:slug` by its slug, such as
+// `/:year/:month/:slug/`. A URL which ends with `/` is written to the
+// `index.html` of that directory.
+var Permalink = ":slug.html"
+
+// Slugify makes a slug of title: ASCII letters and digits are lowercased,
+// Han characters are written in pinyin, one word each, and the words are
+// joined by hyphens. Any other character separates words.
+func Slugify(title string) string {
+	words := []string{}
+	var word strings.Builder
+	end := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, c := range title {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+			word.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			word.WriteRune(c - 'A' + 'a')
+		case c == '\'' || c == '’':
+			// don't and it’s keep together
+		default:
+			end()
+			if syllable, exist := pinyin[c]; exist {
+				words = append(words, syllable)
+			}
+		}
+	}
+	end()
+
+	return strings.Join(words, "-")
+}
+
+// setSlug sets the slug of the article from a `URL:` or `slug:`, which may
+// have slashes around it and a ".html" suffix.
+func (a *Article) setSlug(slug string) {
+	a.Slug = strings.TrimSuffix(strings.Trim(slug, "/"), ".html")
+}
+
+// permalink is the URL of the article by pattern, without a leading slash.
+func (a *Article) permalink(pattern string) string {
+	replacer := strings.NewReplacer(
+		":year", a.Time.Format("2006"),
+		":month", a.Time.Format("01"),
+		":day", a.Time.Format("02"),
+		":slug", a.Slug,
+	)
+
+	return strings.TrimPrefix(replacer.Replace(pattern), "/")
+}
+
+// resolveSlugs gives a URL to the articles of the indexes, whose slugs are
+// made from their titles, in the order of their dates and names. A slug
+// whose URL is taken gets the first free suffix of -2, -3 and so on.
+func resolveSlugs(articles []*Article, names []string, indexes []int, urls map[string]string) {
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a, b := articles[indexes[i]], articles[indexes[j]]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		return names[indexes[i]] < names[indexes[j]]
+	})
+
+	for _, i := range indexes {
+		a, slug := articles[i], articles[i].Slug
+		for n := 2; ; n++ {
+			if _, exist := urls[a.URL]; !exist {
+				break
+			}
+			a.Slug = fmt.Sprintf("%s-%d", slug, n)
+			a.URL = a.permalink(Permalink)
+		}
+		urls[a.URL] = names[i]
+	}
+}
@@ -0,0 +1,95 @@
+package cvblog
+
+import (
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	input := []string{
+		"Hello, World!",
+		"我的第一篇文章",
+		"Go 语言的并发模型",
+		"Don't Panic: 2024 年终总结",
+		"  --Already-Slugged--  ",
+		"女孩 & 绿色",
+		"🎉",
+	}
+
+	output := []string{
+		"hello-world",
+		"wo-de-di-yi-pian-wen-zhang",
+		"go-yu-yan-de-bing-fa-mo-xing",
+		"dont-panic-2024-nian-zhong-zong-jie",
+		"already-slugged",
+		"nv-hai-lv-se",
+		"",
+	}
+
+	for i, v := range input {
+		if slug := Slugify(v); slug != output[i] {
+			t.Fatalf("slugify fail, [%s] vs [%s]", slug, output[i])
+		}
+	}
+}
+
+func TestPermalink(t *testing.T) {
+	defer func(pattern string) { Permalink = pattern }(Permalink)
+
+	input := []string{
+		"Date: 2012-10-25 12:22\nTitle: 旧格式\nTags: \nStatus: \nURL: \n\nbody",
+		"Date: 2012-10-25 12:22\nTitle: A & B\nURL: /given/\n\nbody",
+		"---\ntitle: 前言\ndate: 2024-03-01\nslug: preface.html\n---\nbody",
+	}
+
+	output := [][]string{
+		{"jiu-ge-shi.html", "2012/10/jiu-ge-shi/"},
+		{"given.html", "2012/10/given/"},
+		{"preface.html", "2024/03/preface/"},
+	}
+
+	for i, pattern := range []string{":slug.html", "/:year/:month/:slug/"} {
+		Permalink = pattern
+		for j, v := range input {
+			paper, err := ParseArticle("a.md", []byte(v))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if paper.URL != output[j][i] {
+				t.Fatalf("permalink fail, [%s] vs [%s]", paper.URL, output[j][i])
+			}
+		}
+	}
+}
+
+func TestResolveSlugs(t *testing.T) {
+	input := map[string]string{
+		"a.md": "Date: 2012-10-27 12:22\nTitle: 你好\nURL: \n\nbody",
+		"b.md": "Date: 2012-10-25 12:22\nTitle: 你好\nURL: \n\nbody",
+		"c.md": "Date: 2012-10-26 12:22\nTitle: 你好\nURL: ni-hao\n\nbody",
+		"d.md": "Date: 2012-10-25 12:22\nTitle: 你好!\nURL: \n\nsee {{< post \"ni-hao-2\" >}}",
+	}
+
+	output := map[string]string{
+		"a.md": "ni-hao-4.html",
+		"b.md": "ni-hao-2.html",
+		"c.md": "ni-hao.html",
+		"d.md": "ni-hao-3.html",
+	}
+
+	for _, order := range [][]string{{"a.md", "b.md", "c.md", "d.md"}, {"d.md", "c.md", "b.md", "a.md"}} {
+		inputs := make([][]byte, len(order))
+		for i, name := range order {
+			inputs[i] = []byte(input[name])
+		}
+
+		articles, err := BuildArticles(order, inputs, NewShortcodes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, name := range order {
+			if articles[i].URL != output[name] {
+				t.Fatalf("resolve slugs fail, [%s] vs [%s]", articles[i].URL, output[name])
+			}
+		}
+	}
+}